@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cytificlabs/tr2rl/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply [plan.json]",
+	Short: "Execute a plan saved with 'build --plan-out'",
+	Long: `Runs exactly the operations recorded in a plan file, so a plan can be reviewed
+(e.g. in a pull request) before anything touches the disk.
+
+The plan is applied to the directory it was made for; to build elsewhere,
+make a new plan for that directory. Plans with conflicts are refused, as are
+operations that resolve outside the target directory (plans are plain JSON
+and are re-checked). A file that appeared since the plan was made is never
+overwritten by a "create" operation. If any operation fails, everything the
+plan changed is rolled back.`,
+	Example: `  tr2rl build spec.tree ./out --plan-out plan.json
+  tr2rl apply plan.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read plan '%s': %w", args[0], err)
+		}
+		defer f.Close()
+
+		plan, err := fs.ReadPlan(f)
+		if err != nil {
+			return err
+		}
+
+		noRollback, _ := cmd.Flags().GetBool("no-rollback")
+		noJournal, _ := cmd.Flags().GetBool("no-journal")
+		allowOutside, _ := cmd.Flags().GetBool("allow-outside")
+
+		fmt.Printf("Applying plan in: %s\n", plan.Root)
+		return plan.Execute(fs.ExecuteOptions{
+			Log:          os.Stdout,
+			NoRollback:   noRollback,
+			NoJournal:    noJournal,
+			AllowOutside: allowOutside,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().Bool("allow-outside", false, "allow operations that resolve outside the target directory")
+	applyCmd.Flags().Bool("no-journal", false, "do not record the run in .tr2rl/journal (disables 'tr2rl undo')")
+	applyCmd.Flags().Bool("no-rollback", false, "keep partial results if the plan fails (default: undo everything)")
+}
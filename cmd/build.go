@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
-	"github.com/spf13/cobra"
+	"github.com/cytificlabs/tr2rl/internal/archive"
 	"github.com/cytificlabs/tr2rl/internal/fs"
 	"github.com/cytificlabs/tr2rl/internal/parser"
+	"github.com/spf13/cobra"
 )
 
 var buildCmd = &cobra.Command{
@@ -17,14 +20,27 @@ Input can be:
   - A file path
   - Stdin (pipe)
   - Clipboard (--clipboard)
+  - Markdown / chat answers (the most tree-like fenced block, or --block N)
 
 Safety:
   - Defaults to WRITING files.
-  - Use --dry-run to preview changes safely.
-  - Will NOT overwrite existing files unless --force is used.    
+  - Use --dry-run to preview changes safely (computed from the real state of the target).
+  - Use --plan-out to save the plan for review and run it later with 'tr2rl apply'.
+  - Will NOT overwrite existing files unless --force is used.
+  - Builds are atomic: if anything fails, created paths are removed and
+    overwritten files restored (disable with --no-rollback).
+  - Paths that would land outside the output directory ("../x", or through an
+    existing symlink) are refused unless --allow-outside is given.
+  - --portable refuses names that break on another OS (aux.go, con/, "a:b",
+    trailing dots, Readme.md vs README.md); --sanitize rewrites them instead.
+  - Every build is journaled in <dir>/.tr2rl so 'tr2rl undo' can reverse it.    
+  - --out-archive writes a .zip or .tar(.gz) instead of a directory; an existing
+    archive is only replaced with --force.
 
 Features:
-  - --populate: Intelligently fills created files with boilerplate (e.g. package main for Go).`,
+  - --populate: Intelligently fills created files with boilerplate (e.g. package main for Go).
+  - Markdown answers: code blocks captioned with a file path ("### src/main.go")
+    become the contents of that file.`,
 	Example: `  # Preview what would happen
   tr2rl build structure.txt --dry-run
 
@@ -32,13 +48,26 @@ Features:
   tr2rl build structure.txt ./my-output
 
   # Create from clipboard and auto-fill content
-  tr2rl build --clipboard --populate`,
+  tr2rl build --clipboard --populate
+
+  # Save a reviewable plan instead of building, then apply exactly that plan
+  tr2rl build structure.txt ./my-output --plan-out plan.json
+  tr2rl apply plan.json
+
+  # Use the second code block of a pasted chat answer
+  tr2rl build answer.md --block 2
+
+  # Ship a populated starter without creating it locally
+  tr2rl build structure.txt --populate --out-archive starter.zip`,
 	Args: cobra.MaximumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		in, err := readInputFromCmd(cmd, args[:min(1, len(args))])
+		res, err := parseInputFromCmd(cmd, args[:min(1, len(args))])
 		if err != nil {
 			return err
 		}
+		if err := applyPortability(cmd, &res); err != nil {
+			return err
+		}
 
 		outDir := "."
 		if len(args) >= 2 {
@@ -48,16 +77,51 @@ Features:
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
 		force, _ := cmd.Flags().GetBool("force")
 
-		res := parser.Parse(in)
+		populate, _ := cmd.Flags().GetBool("populate")
+		noRollback, _ := cmd.Flags().GetBool("no-rollback")
+		noJournal, _ := cmd.Flags().GetBool("no-journal")
+		allowOutside, _ := cmd.Flags().GetBool("allow-outside")
+		planOut, _ := cmd.Flags().GetString("plan-out")
+		archiveOut, _ := cmd.Flags().GetString("out-archive")
+
+		opts := fs.ApplyOptions{
+			DryRun:       dryRun,
+			Force:        force,
+			Populate:     populate,
+			NoRollback:   noRollback,
+			NoJournal:    noJournal,
+			AllowOutside: allowOutside,
+		}
+
+		if archiveOut != "" {
+			if len(args) >= 2 || planOut != "" {
+				return fmt.Errorf("--out-archive cannot be combined with an output directory or --plan-out")
+			}
+			printDiagnostics(os.Stderr, res.Diagnostics, false)
+			return writeArchive(archiveOut, res, opts)
+		}
+
+		diags := res.Diagnostics
+		var escapes []parser.Diagnostic
+		if !allowOutside {
+			escapes = fs.CheckPaths(outDir, res.Nodes)
+			diags = mergeDiagnostics(diags, escapes)
+		}
+		printDiagnostics(os.Stderr, diags, false)
+		if len(escapes) > 0 {
+			return fmt.Errorf("refusing to build: %d path(s) resolve outside %s (use --allow-outside if this is intended)", len(escapes), outDir)
+		}
+
+		if planOut != "" {
+			return writePlanFile(planOut, outDir, res, opts)
+		}
 
 		fmt.Printf("Building structure in: %s\n", outDir)
 		if dryRun {
 			fmt.Println("--- DRY RUN (No changes will be made) ---")
 		}
 
-		populate, _ := cmd.Flags().GetBool("populate")
-
-		return fs.Apply(outDir, res.Nodes, fs.ApplyOptions{DryRun: dryRun, Force: force, Populate: populate})
+		return fs.Apply(outDir, res.Nodes, opts)
 	},
 }
 
@@ -68,4 +132,84 @@ func init() {
 	buildCmd.Flags().Bool("force", false, "overwrite existing files")
 	// Auto-populate is opt-in to avoid surprising users.
 	buildCmd.Flags().Bool("populate", false, "auto-fill files with smart boilerplate")
+	buildCmd.Flags().Bool("no-rollback", false, "keep partial results if the build fails (default: undo everything)")
+	buildCmd.Flags().Bool("no-journal", false, "do not record the build in .tr2rl/journal (disables 'tr2rl undo')")
+	addPortabilityFlags(buildCmd)
+	buildCmd.Flags().Bool("allow-outside", false, "allow paths that resolve outside the output directory (via .. or symlinks)")
+	buildCmd.Flags().String("plan-out", "", "write the execution plan to this file instead of building")
+	buildCmd.Flags().String("out-archive", "", "build into a .zip, .tar or .tar.gz file instead of a directory")
+}
+
+// writeArchive builds the tree into a new archive file. The archive is
+// written to a temporary file next to it and renamed at the end, so a failed
+// build leaves nothing behind. Archives never hold paths outside their root.
+func writeArchive(file string, res parser.Result, opts fs.ApplyOptions) error {
+	format := archive.Format(file)
+	if format == "" {
+		return fmt.Errorf("%s: --out-archive must end in .zip, .tar, .tar.gz or .tgz", file)
+	}
+	if _, err := os.Stat(file); err == nil && !opts.Force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", file)
+	}
+	opts.AllowOutside = false
+
+	fmt.Printf("Building structure into: %s\n", file)
+	if opts.DryRun {
+		fmt.Println("--- DRY RUN (No changes will be made) ---")
+		return fs.ApplyTo(nil, res.Nodes, opts)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tr2rl-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create archive '%s': %w", file, err)
+	}
+	aw, err := archive.NewWriter(tmp, format)
+	if err == nil {
+		err = fs.ApplyTo(aw, res.Nodes, opts)
+	}
+	if err == nil {
+		err = aw.Close()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	fmt.Printf("Wrote %s\n", file)
+	return nil
+}
+
+// writePlanFile saves the plan for outDir. The root is stored as an absolute
+// path, so 'tr2rl apply' builds in the same place from any directory.
+func writePlanFile(file, outDir string, res parser.Result, opts fs.ApplyOptions) error {
+	root, err := filepath.Abs(outDir)
+	if err != nil {
+		return err
+	}
+	plan, err := fs.NewPlan(root, res.Nodes, opts)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create plan file '%s': %w", file, err)
+	}
+	defer f.Close()
+
+	if err := fs.WritePlan(f, plan); err != nil {
+		return err
+	}
+
+	plan.Print(os.Stdout)
+	fmt.Printf("\nPlan saved to %s. Run 'tr2rl apply %s' to execute it.\n", file, file)
+	return nil
 }
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cytificlabs/tr2rl/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+// errDrift makes check exit non-zero without printing usage.
+var errDrift = errors.New("directory does not match spec")
+
+var checkCmd = &cobra.Command{
+	Use:     "check [spec] [dir]",
+	Aliases: []string{"diff"},
+	Short:   "Compare a tree spec against a directory (CI friendly)",
+	Long: `Parses the spec and compares it with the target directory, reporting:
+
+  missing        paths in the spec that do not exist on disk
+  extra          paths on disk that are not in the spec
+  kind-mismatch  a file where the spec expects a directory, or vice versa
+
+Exits with a non-zero status when the directory has drifted from the spec,
+so a committed .tree file can enforce a repository layout in CI.
+.gitignore files are honoured when looking for extra paths.`,
+	Example: `  # Verify the repository still matches its documented layout
+  tr2rl check layout.tree .
+
+  # Only care that required paths exist
+  tr2rl check layout.tree . --ignore-extra
+
+  # Machine-readable report
+  tr2rl diff layout.tree ./service --json`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		res, err := parseInputFromCmd(cmd, args[:min(1, len(args))])
+		if err != nil {
+			return err
+		}
+
+		dir := "."
+		if len(args) >= 2 {
+			dir = args[1]
+		}
+
+		ignoreExtra, _ := cmd.Flags().GetBool("ignore-extra")
+		exclude, _ := cmd.Flags().GetStringSlice("exclude")
+		noGitignore, _ := cmd.Flags().GetBool("no-gitignore")
+
+		report, err := fs.Compare(dir, res.Nodes, fs.CompareOptions{
+			IgnoreExtra: ignoreExtra,
+			Scan:        fs.ScanOptions{Exclude: exclude, NoGitignore: noGitignore},
+		})
+		if err != nil {
+			return err
+		}
+
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		if jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			printDriftReport(report)
+		}
+
+		if !report.Clean() {
+			return errDrift
+		}
+		return nil
+	},
+}
+
+func printDriftReport(report fs.Report) {
+	if report.Clean() {
+		fmt.Printf("[OK] %s matches the spec\n", report.Root)
+		return
+	}
+
+	for _, d := range report.Drift {
+		switch d.Kind {
+		case fs.KindMismatch:
+			fmt.Printf("%-14s %s (spec: %s, disk: %s)\n", d.Kind, d.Path, d.Expected, d.Actual)
+		case fs.Missing:
+			fmt.Printf("%-14s %s\n", d.Kind, displayPath(d.Path, d.Expected))
+		default:
+			fmt.Printf("%-14s %s\n", d.Kind, displayPath(d.Path, d.Actual))
+		}
+	}
+	fmt.Printf("\n%d difference(s) between the spec and %s\n", len(report.Drift), report.Root)
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.Flags().Bool("json", false, "output the report as JSON")
+	checkCmd.Flags().Bool("ignore-extra", false, "do not report paths that exist on disk but not in the spec")
+	checkCmd.Flags().StringSlice("exclude", nil, "gitignore-style glob to leave out when looking for extra paths (repeatable)")
+	checkCmd.Flags().Bool("no-gitignore", false, "also report extra paths matched by .gitignore")
+}
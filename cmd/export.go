@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cytificlabs/tr2rl/internal/export"
+	"github.com/cytificlabs/tr2rl/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Print a script that creates the tree (bash, PowerShell, cmd, Dockerfile, Makefile)",
+	Long: `Turns a tree spec into a script that builds the same structure 'tr2rl build'
+would, for machines where tr2rl is not installed: CI jobs, containers, READMEs.
+
+The script is printed to stdout and nothing is written to disk. It is
+idempotent: directories are created with mkdir -p / New-Item -Force, and files
+that already exist are left alone unless --force is given. File contents
+(from the spec, or from --populate) are embedded as quoted heredocs, and names
+with spaces or special characters are quoted for the target shell.
+
+Shells:
+  bash        POSIX shell script (also runs under sh)
+  powershell  PowerShell 5.1 and later
+  cmd         Windows batch file
+  dockerfile  a RUN heredoc to paste into a Dockerfile (BuildKit)
+  makefile    a "scaffold" target (GNU make)`,
+	Example: `  # A script to commit next to the project
+  tr2rl export structure.txt --shell bash > scaffold.sh
+
+  # Scaffold inside a container image
+  tr2rl export structure.txt --shell dockerfile --populate >> Dockerfile
+
+  # Same tree for Windows users
+  tr2rl export structure.txt --shell powershell > scaffold.ps1`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		res, err := parseInputFromCmd(cmd, args)
+		if err != nil {
+			return err
+		}
+		if err := applyPortability(cmd, &res); err != nil {
+			return err
+		}
+		printDiagnostics(os.Stderr, res.Diagnostics, false)
+
+		shell, _ := cmd.Flags().GetString("shell")
+		force, _ := cmd.Flags().GetBool("force")
+		populate, _ := cmd.Flags().GetBool("populate")
+
+		plan := fs.NewEmptyPlan(res.Nodes, fs.ApplyOptions{Populate: populate})
+		if n := plan.Count(fs.OpConflict); n > 0 {
+			for _, op := range plan.Ops {
+				if op.Kind == fs.OpConflict {
+					fmt.Fprintf(os.Stderr, "[CONFLICT] %s: %s\n", op.Path, op.Reason)
+				}
+			}
+			return fmt.Errorf("refusing to export: %d path(s) resolve outside the output directory", n)
+		}
+		return export.Write(os.Stdout, plan, shell, export.Options{Force: force})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().String("shell", "bash", "Script flavour: "+strings.Join(export.Shells, ", "))
+	exportCmd.Flags().Bool("populate", false, "embed smart boilerplate in files that have no content")
+	exportCmd.Flags().Bool("force", false, "make the script overwrite existing files")
+	addPortabilityFlags(exportCmd)
+}
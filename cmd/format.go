@@ -1,9 +1,11 @@
 package cmd
 
 import (
-	"github.com/spf13/cobra"
-	"github.com/cytificlabs/tr2rl/internal/parser"
+	"os"
+	"strings"
+
 	"github.com/cytificlabs/tr2rl/internal/printer"
+	"github.com/spf13/cobra"
 )
 
 var formatCmd = &cobra.Command{
@@ -11,33 +13,41 @@ var formatCmd = &cobra.Command{
 	Short: "Pretty-print a standard tree from any input",
 	Long: `Takes any messy input (indented lists, partial trees, path lists) and outputs 
 a perfectly formatted Unicode tree structure. Useful for documentation or verifying 
-how tr2rl interprets your input.`,
+how tr2rl interprets your input.
+
+With --to, the same tree is written as a Markdown list, JSON or YAML spec, path
+list, or HTML list instead. Every format can be read back by tr2rl.`,
 	Example: `  # Clean up a messy list from clipboard
   tr2rl format --clipboard
 
   # verify how a Windows tree is parsed
-  tr2rl format windows_output.txt`,
+  tr2rl format windows_output.txt
+
+  # Turn a tree into a YAML spec
+  tr2rl format spec.tree --to yaml > layout.yaml`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		in, err := readInputFromCmd(cmd, args)
+		res, err := parseInputFromCmd(cmd, args)
 		if err != nil {
 			return err
 		}
 
-		res := parser.Parse(in)
-
 		// Get style flag
 		style, _ := cmd.Flags().GetString("style")
 
 		// Map simple flag to options
 		opts := printer.Options{Style: style}
 
-		printer.PrintTreeWithOptions(res.Nodes, opts)
-		return nil
+		to, _ := cmd.Flags().GetString("to")
+		if jsonOut, _ := cmd.Flags().GetBool("json"); jsonOut && !cmd.Flags().Changed("to") {
+			to = "json"
+		}
+		return printer.Render(os.Stdout, res.Nodes, to, opts)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(formatCmd)
 	formatCmd.Flags().String("style", "unicode", "Output style: 'unicode' (default) or 'ascii'")
+	formatCmd.Flags().String("to", "tree", "Output format: "+strings.Join(printer.Formats, ", "))
 }
@@ -4,24 +4,38 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/spf13/cobra"
+	"github.com/cytificlabs/tr2rl/internal/archive"
 	"github.com/cytificlabs/tr2rl/internal/clipboard"
+	"github.com/cytificlabs/tr2rl/internal/parser"
+	"github.com/cytificlabs/tr2rl/internal/portable"
+	"github.com/spf13/cobra"
 )
 
-// Helper to standardise input reading
-func readInputFromCmd(cmd *cobra.Command, args []string) (string, error) {
+// Helper to standardise input reading. Archives (.zip, .tar, .tar.gz) are not
+// text: their entries are returned as nodes instead.
+func readInputFromCmd(cmd *cobra.Command, args []string) (string, []parser.Node, error) {
 	useClipboard, _ := cmd.Flags().GetBool("clipboard")
 	if useClipboard {
-		return clipboard.ReadAll()
+		text, err := clipboard.ReadAll()
+		return text, nil, err
 	}
 
-	if len(args) > 0 {
+	if len(args) > 0 && args[0] != "-" {
+		if archive.Format(args[0]) != "" {
+			nodes, err := archive.ReadNodes(args[0])
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to read archive '%s': %w", args[0], err)
+			}
+			return "", nodes, nil
+		}
 		content, err := os.ReadFile(args[0])
 		if err != nil {
-			return "", fmt.Errorf("failed to read file '%s': %w", args[0], err)
+			return "", nil, fmt.Errorf("failed to read file '%s': %w", args[0], err)
 		}
-		return string(content), nil
+		return string(content), nil, nil
 	}
 
 	// Read from stdin if valid
@@ -29,10 +43,117 @@ func readInputFromCmd(cmd *cobra.Command, args []string) (string, error) {
 	if (stat.Mode() & os.ModeCharDevice) == 0 {
 		content, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
-		return string(content), nil
+		return string(content), nil, nil
+	}
+
+	return "", nil, fmt.Errorf("no input provided.\nTry:\n  tr2rl build file.txt\n  cat file.txt | tr2rl build -\n  tr2rl build --clipboard")
+}
+
+// parseInputFromCmd reads the command input and parses it using the shared
+// parser flags (e.g. --block).
+func parseInputFromCmd(cmd *cobra.Command, args []string) (parser.Result, error) {
+	in, nodes, err := readInputFromCmd(cmd, args)
+	if err != nil {
+		return parser.Result{}, err
+	}
+	if nodes != nil {
+		return parser.FromNodes(nodes, archive.Format(args[0])), nil
+	}
+
+	block, _ := cmd.Flags().GetInt("block")
+	opts := parser.Options{Block: block}
+	if len(args) > 0 {
+		opts.Format = formatHint(args[0])
+	}
+	return parser.ParseWithOptions(in, opts)
+}
+
+// formatHint picks the input format implied by a file name. Only YAML needs
+// the hint: everything else is recognised from the content.
+func formatHint(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return ""
+}
+
+// applyPortability runs the cross-platform filename checks requested with
+// --portable (report, and fail on any problem) or --sanitize (rewrite names).
+func applyPortability(cmd *cobra.Command, res *parser.Result) error {
+	sanitize, _ := cmd.Flags().GetBool("sanitize")
+	strict, _ := cmd.Flags().GetBool("portable")
+
+	if sanitize {
+		var diags []parser.Diagnostic
+		res.Nodes, diags = portable.Sanitize(res.Nodes)
+		res.Normalized = parser.Normalize(res.Nodes)
+		res.Diagnostics = append(res.Diagnostics, diags...)
+		return nil
+	}
+	if !strict {
+		return nil
 	}
 
-	return "", fmt.Errorf("no input provided.\nTry:\n  tr2rl build file.txt\n  cat file.txt | tr2rl build -\n  tr2rl build --clipboard")
+	diags := portable.Check(res.Nodes)
+	res.Diagnostics = append(res.Diagnostics, diags...)
+	if len(diags) > 0 {
+		printDiagnostics(os.Stderr, res.Diagnostics, false)
+		return fmt.Errorf("%d portability problem(s) found (use --sanitize to rewrite the names)", len(diags))
+	}
+	return nil
+}
+
+// addPortabilityFlags registers --portable and --sanitize on cmd.
+func addPortabilityFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("portable", false, "fail if any name cannot be checked out on Windows, macOS and Linux alike")
+	cmd.Flags().Bool("sanitize", false, "rewrite names that are not portable (reserved, illegal characters, collisions)")
+}
+
+// printDiagnostics writes parser diagnostics to w, one per line.
+// Info-level entries are only shown when verbose is set.
+func printDiagnostics(w io.Writer, diags []parser.Diagnostic, verbose bool) {
+	shown := make([]parser.Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		if d.Severity == parser.SeverityInfo && !verbose {
+			continue
+		}
+		shown = append(shown, d)
+	}
+	if len(shown) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\nDiagnostics:")
+	for _, d := range shown {
+		fmt.Fprintln(w, "- "+d.String())
+	}
+}
+
+// mergeDiagnostics appends extra to diags, skipping entries that repeat an
+// existing diagnostic (same code on the same line).
+func mergeDiagnostics(diags, extra []parser.Diagnostic) []parser.Diagnostic {
+	seen := make(map[string]bool, len(diags))
+	key := func(d parser.Diagnostic) string { return fmt.Sprintf("%s:%d", d.Code, d.Line) }
+	for _, d := range diags {
+		seen[key(d)] = true
+	}
+
+	out := append([]parser.Diagnostic{}, diags...)
+	for _, d := range extra {
+		if !seen[key(d)] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// displayPath appends a trailing slash to directories, matching Result.Normalized.
+func displayPath(p string, kind parser.NodeKind) string {
+	if kind == parser.Dir {
+		return p + "/"
+	}
+	return p
 }
@@ -18,6 +18,7 @@ It handles:
   - Indented lists (Notion/TextEdit style)
   - Path lists (search results)
   - Mixed/messy input with comments
+  - Markdown / chat answers (the tree is taken from a fenced code block)
 
 Default behavior produces REAL changes. Use --dry-run to preview.`,
 	Example: `  # Build from a file
@@ -39,6 +40,7 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().Bool("clipboard", false, "read input from clipboard")
+	rootCmd.PersistentFlags().Int("block", 0, "fenced code block to use from Markdown input (1-based, 0 = auto)")
 	rootCmd.PersistentFlags().String("color", "auto", "color output: auto|always|never")
 	rootCmd.PersistentFlags().Bool("json", false, "JSON output (for spec/format)")
 
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"github.com/cytificlabs/tr2rl/internal/fs"
+	"github.com/cytificlabs/tr2rl/internal/printer"
+	"github.com/spf13/cobra"
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan [dir]",
+	Short: "Turn an existing directory into a tree spec",
+	Long: `Walks a directory and prints it as a tree spec. This is the reverse of build:
+use it to document a repository or to turn a hand-made project into a reusable template.
+
+The output parses back into the same structure, so it can be fed straight into build.
+.gitignore files are honoured and the .git directory is always skipped.`,
+	Example: `  # Document the current repository
+  tr2rl scan
+
+  # Two levels deep, without tests, in ASCII
+  tr2rl scan ./my-project --max-depth 2 --exclude '*_test.go' --style ascii
+
+  # Clone a layout (without contents) somewhere else
+  tr2rl scan ./template | tr2rl build - ./new-project`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		maxDepth, _ := cmd.Flags().GetInt("max-depth")
+		exclude, _ := cmd.Flags().GetStringSlice("exclude")
+		noGitignore, _ := cmd.Flags().GetBool("no-gitignore")
+
+		nodes, err := fs.Scan(dir, fs.ScanOptions{
+			MaxDepth:    maxDepth,
+			Exclude:     exclude,
+			NoGitignore: noGitignore,
+		})
+		if err != nil {
+			return err
+		}
+
+		style, _ := cmd.Flags().GetString("style")
+		printer.PrintTreeWithOptions(nodes, printer.Options{Style: style})
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scanCmd)
+	scanCmd.Flags().String("style", "unicode", "Output style: 'unicode' (default) or 'ascii'")
+	scanCmd.Flags().Int("max-depth", 0, "maximum depth to descend (0 = unlimited)")
+	scanCmd.Flags().StringSlice("exclude", nil, "gitignore-style glob to leave out (repeatable)")
+	scanCmd.Flags().Bool("no-gitignore", false, "include files matched by .gitignore")
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/cytificlabs/tr2rl/internal/parser"
 )
 
 var specCmd = &cobra.Command{
@@ -14,12 +13,13 @@ var specCmd = &cobra.Command{
 	Short: "Normalize/repair a tree spec (no disk writes)",
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		in, err := readInputFromCmd(cmd, args)
+		res, err := parseInputFromCmd(cmd, args)
 		if err != nil {
 			return err
 		}
-
-		res := parser.Parse(in)
+		if err := applyPortability(cmd, &res); err != nil {
+			return err
+		}
 
 		jsonOut, _ := cmd.Flags().GetBool("json")
 		if jsonOut {
@@ -29,18 +29,16 @@ var specCmd = &cobra.Command{
 		}
 
 		fmt.Println(res.Normalized)
-		if len(res.Warnings) > 0 {
-			fmt.Fprintln(os.Stderr, "\nWarnings:")
-			for _, w := range res.Warnings {
-				fmt.Fprintln(os.Stderr, "- "+w)
-			}
-		}
+
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		printDiagnostics(os.Stderr, res.Diagnostics, verbose)
 		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(specCmd)
-	specCmd.Flags().BoolP("verbose", "v", false, "show parsing details")
+	specCmd.Flags().BoolP("verbose", "v", false, "show parsing details (including info diagnostics)")
 	specCmd.Flags().Bool("json", false, "output result as JSON")
+	addPortabilityFlags(specCmd)
 }
@@ -3,8 +3,8 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/spf13/cobra"
 	"github.com/cytificlabs/tr2rl/internal/templates"
+	"github.com/spf13/cobra"
 )
 
 var templateCmd = &cobra.Command{
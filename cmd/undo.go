@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cytificlabs/tr2rl/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo [id]",
+	Short: "Reverse a previous build using its journal",
+	Long: `Every build records what it created and overwrote in .tr2rl/journal inside
+the output directory. undo reverses the most recent build (or the one with the
+given id): created files and directories are removed and overwritten files are
+restored from their backups.
+
+Files that changed since the build are never deleted or restored unless --force
+is given. Directories that gained new content are kept. Every path in the
+journal must lie inside the directory, unless the build was made with
+--allow-outside and --allow-outside is given here too.`,
+	Example: `  # Oops, wrong clipboard contents
+  tr2rl build --clipboard ./app
+  tr2rl undo --dir ./app
+
+  # List recorded builds and undo a specific one
+  tr2rl undo --list
+  tr2rl undo 20261016-101530`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+
+		list, _ := cmd.Flags().GetBool("list")
+		if list {
+			ids, err := fs.ListJournals(dir)
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				fmt.Printf("No builds recorded in %s\n", dir)
+			}
+			for _, id := range ids {
+				fmt.Println(id)
+			}
+			return nil
+		}
+
+		id := ""
+		if len(args) > 0 {
+			id = args[0]
+		}
+		j, err := fs.LoadJournal(dir, id)
+		if err != nil {
+			return err
+		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		allowOutside, _ := cmd.Flags().GetBool("allow-outside")
+		fmt.Printf("Undoing build %s in: %s\n", j.ID, dir)
+		return fs.Undo(dir, j, fs.UndoOptions{Log: os.Stdout, Force: force, AllowOutside: allowOutside})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+	undoCmd.Flags().String("dir", ".", "output directory of the build to undo")
+	undoCmd.Flags().Bool("list", false, "list recorded builds instead of undoing")
+	undoCmd.Flags().Bool("force", false, "undo even if files changed since the build")
+	undoCmd.Flags().Bool("allow-outside", false, "undo a build made with --allow-outside, whose paths may lie outside the directory")
+}
@@ -0,0 +1,139 @@
+// Package archive reads the layout of .zip and .tar(.gz) files from their
+// headers, so an archive can be used as a tree spec without extracting it.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+// Format names the archive format of a file from its extension:
+// "zip", "tar", "tar.gz", or "" if it is not an archive we read.
+func Format(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	}
+	return ""
+}
+
+// ReadNodes lists the entries of an archive as nodes, keeping sizes,
+// permission bits and symlink targets. File contents are not read. Paths
+// are returned as stored; parser.FromNodes cleans them and adds implied
+// directories.
+func ReadNodes(name string) ([]parser.Node, error) {
+	switch Format(name) {
+	case "zip":
+		return readZip(name)
+	case "tar", "tar.gz":
+		return readTar(name)
+	}
+	return nil, fmt.Errorf("%s: not a .zip, .tar or .tar.gz file", name)
+}
+
+// Zip creators whose external attributes carry Unix permission bits.
+const (
+	creatorUnix  = 3
+	creatorMacOS = 19
+)
+
+func readZip(name string) ([]parser.Node, error) {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	nodes := make([]parser.Node, 0, len(r.File))
+	for _, f := range r.File {
+		n := parser.Node{Path: strings.TrimSuffix(f.Name, "/"), Kind: parser.File, Size: int64(f.UncompressedSize64)}
+		switch {
+		case strings.HasSuffix(f.Name, "/") || f.Mode().IsDir():
+			n.Kind = parser.Dir
+			n.Size = 0
+		case f.Mode()&fs.ModeSymlink != 0:
+			// A zip symlink stores its target as the entry's contents.
+			target, err := readZipEntry(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", name, f.Name, err)
+			}
+			n.Kind, n.Target, n.Size = parser.Symlink, target, 0
+		}
+		// Archives made on Windows carry no modes; zip then reports 0666/0777.
+		if creator := f.CreatorVersion >> 8; creator == creatorUnix || creator == creatorMacOS {
+			n.Mode = f.Mode().Perm()
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func readZipEntry(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, 4096))
+	return string(data), err
+}
+
+func readTar(name string) ([]parser.Node, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var src io.Reader = f
+	if Format(name) == "tar.gz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	var nodes []parser.Node
+	tr := tar.NewReader(src)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		n := parser.Node{Path: strings.TrimSuffix(h.Name, "/"), Mode: fs.FileMode(h.Mode).Perm()}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			n.Kind = parser.Dir
+		case tar.TypeSymlink:
+			n.Kind = parser.Symlink
+			n.Target = h.Linkname
+		case tar.TypeReg, tar.TypeLink:
+			n.Kind = parser.File
+			n.Size = h.Size
+		default:
+			continue // devices, fifos, global headers
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+func TestReadNodes_TarGz(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "release.tar.gz")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	headers := []*tar.Header{
+		{Name: "./", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "./bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "./bin/run.sh", Typeflag: tar.TypeReg, Mode: 0755, Size: 2},
+		{Name: "./docs/guide.md", Typeflag: tar.TypeReg, Mode: 0644, Size: 2},
+	}
+	for _, h := range headers {
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Size > 0 {
+			tw.Write([]byte("x\n"))
+		}
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	nodes, err := ReadNodes(name)
+	if err != nil {
+		t.Fatalf("ReadNodes: %v", err)
+	}
+	res := parser.FromNodes(nodes, Format(name))
+
+	want := "bin/\nbin/run.sh\ndocs/\ndocs/guide.md"
+	if res.Normalized != want {
+		t.Errorf("unexpected nodes:\n%s\nwant:\n%s", res.Normalized, want)
+	}
+	for _, n := range res.Nodes {
+		if n.Path == "bin/run.sh" && (n.Mode != 0755 || n.Size != 2) {
+			t.Errorf("bin/run.sh: mode %o size %d, want 755 2", n.Mode, n.Size)
+		}
+	}
+}
+
+func TestReadNodes_Zip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "site.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, entry := range []struct {
+		name string
+		mode os.FileMode
+	}{
+		{"site/", os.ModeDir | 0755},
+		{"site/index.html", 0600},
+	} {
+		h := &zip.FileHeader{Name: entry.name}
+		h.SetMode(entry.mode)
+		if _, err := zw.CreateHeader(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	zw.Close()
+	f.Close()
+
+	nodes, err := ReadNodes(name)
+	if err != nil {
+		t.Fatalf("ReadNodes: %v", err)
+	}
+	if len(nodes) != 2 || nodes[0].Kind != parser.Dir || nodes[1].Path != "site/index.html" {
+		t.Fatalf("unexpected nodes: %+v", nodes)
+	}
+	if nodes[1].Mode != 0600 {
+		t.Errorf("site/index.html: mode %o, want 600", nodes[1].Mode)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for name, want := range map[string]string{
+		"a.zip": "zip", "a.TAR": "tar", "a.tar.gz": "tar.gz", "a.tgz": "tar.gz", "a.tree": "",
+	} {
+		if got := Format(name); got != want {
+			t.Errorf("Format(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
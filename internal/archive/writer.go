@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Writer streams a build into a .zip, .tar or .tar.gz archive. It satisfies
+// the Dest interface of internal/fs, so a build can go straight into an
+// archive without touching disk.
+type Writer struct {
+	zw      *zip.Writer
+	tw      *tar.Writer
+	gz      *gzip.Writer
+	modTime time.Time
+}
+
+// NewWriter starts an archive of the given format ("zip", "tar" or "tar.gz",
+// as returned by Format) on w. Close must be called to finish it.
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	aw := &Writer{modTime: time.Now()}
+	switch format {
+	case "zip":
+		aw.zw = zip.NewWriter(w)
+	case "tar":
+		aw.tw = tar.NewWriter(w)
+	case "tar.gz":
+		aw.gz = gzip.NewWriter(w)
+		aw.tw = tar.NewWriter(aw.gz)
+	default:
+		return nil, fmt.Errorf("unknown archive format %q (want zip, tar or tar.gz)", format)
+	}
+	return aw, nil
+}
+
+// Mkdir adds a directory entry.
+func (w *Writer) Mkdir(name string, perm fs.FileMode) error {
+	if w.zw != nil {
+		h := &zip.FileHeader{Name: name + "/", Modified: w.modTime}
+		h.SetMode(fs.ModeDir | perm.Perm())
+		_, err := w.zw.CreateHeader(h)
+		return err
+	}
+	return w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     int64(perm.Perm()),
+		ModTime:  w.modTime,
+	})
+}
+
+// WriteFile adds a regular file with the given contents.
+func (w *Writer) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	var dst io.Writer
+	if w.zw != nil {
+		h := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: w.modTime}
+		h.SetMode(perm.Perm())
+		f, err := w.zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+		dst = f
+	} else {
+		err := w.tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     int64(perm.Perm()),
+			Size:     int64(len(data)),
+			ModTime:  w.modTime,
+		})
+		if err != nil {
+			return err
+		}
+		dst = w.tw
+	}
+	_, err := dst.Write(data)
+	return err
+}
+
+// Symlink adds a symbolic link to target.
+func (w *Writer) Symlink(target, name string) error {
+	if w.zw != nil {
+		// Zip has no link type: the mode marks the entry and the data is the target.
+		h := &zip.FileHeader{Name: name, Method: zip.Store, Modified: w.modTime}
+		h.SetMode(fs.ModeSymlink | 0777)
+		f, err := w.zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, target)
+		return err
+	}
+	return w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     name,
+		Linkname: target,
+		Mode:     0777,
+		ModTime:  w.modTime,
+	})
+}
+
+// Close writes the archive trailer. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.zw != nil {
+		return w.zw.Close()
+	}
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
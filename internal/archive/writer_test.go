@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriter_RoundTrip(t *testing.T) {
+	for _, ext := range []string{".zip", ".tar", ".tar.gz"} {
+		t.Run(ext, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "starter"+ext)
+			f, err := os.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := NewWriter(f, Format(name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Mkdir("bin", 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteFile("bin/run.sh", []byte("#!/bin/sh\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteFile("README.md", nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Symlink("bin/run.sh", "run"); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			nodes, err := ReadNodes(name)
+			if err != nil {
+				t.Fatalf("ReadNodes: %v", err)
+			}
+			want := map[string]int64{"bin": 0, "bin/run.sh": 10, "README.md": 0, "run": 0}
+			if len(nodes) != len(want) {
+				t.Fatalf("got %d entries, want %d: %+v", len(nodes), len(want), nodes)
+			}
+			for _, n := range nodes {
+				size, ok := want[n.Path]
+				if !ok || n.Size != size {
+					t.Errorf("unexpected entry %s (size %d)", n.Path, n.Size)
+				}
+				if n.Path == "bin/run.sh" && n.Mode != 0755 {
+					t.Errorf("bin/run.sh: mode %o, want 755", n.Mode)
+				}
+				if n.Path == "run" && n.Target != "bin/run.sh" {
+					t.Errorf("run: %+v, want a symlink to bin/run.sh", n)
+				}
+			}
+		})
+	}
+}
+
+func TestWriter_ZipContents(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.zip")
+	f, _ := os.Create(name)
+	w, _ := NewWriter(f, "zip")
+	w.WriteFile("main.go", []byte("package main\n"), 0644)
+	w.Close()
+	f.Close()
+
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	rc, err := r.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	buf := make([]byte, 64)
+	n, _ := rc.Read(buf)
+	if string(buf[:n]) != "package main\n" {
+		t.Errorf("contents = %q", buf[:n])
+	}
+}
+
+func TestNewWriter_UnknownFormat(t *testing.T) {
+	if _, err := NewWriter(nil, "rar"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,92 @@
+package content
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// commentStyle is how a language writes a comment: a prefix for every line,
+// or an opening and closing pair around the whole text.
+type commentStyle struct {
+	line       string
+	open, done string
+}
+
+// styleFor picks the comment syntax for a file from its name or extension.
+// ok is false for files without comments (JSON, plain text, unknown types).
+func styleFor(path string) (style commentStyle, ok bool) {
+	base := strings.ToLower(filepath.Base(path))
+	ext := strings.ToLower(filepath.Ext(path))
+
+	switch base {
+	case "makefile", "dockerfile", "containerfile", "gemfile", "rakefile", "vagrantfile", "procfile", "cmakelists.txt", "requirements.txt":
+		return commentStyle{line: "# "}, true
+	case "jenkinsfile":
+		return commentStyle{line: "// "}, true
+	}
+
+	switch {
+	case isSlashComment(ext):
+		return commentStyle{line: "// "}, true
+	case isHashComment(ext):
+		return commentStyle{line: "# "}, true
+	}
+	switch ext {
+	case ".mjs", ".cjs", ".h", ".hpp", ".cxx", ".m", ".proto", ".jsonc", ".gradle", ".groovy", ".sass", ".scss", ".less", ".zig", ".sol":
+		return commentStyle{line: "// "}, true
+	case ".bash", ".zsh", ".fish", ".ps1", ".psm1", ".tf", ".hcl", ".graphql", ".gql", ".cmake", ".ex", ".exs", ".nix", ".jl", ".cfg", ".env", ".dockerignore", ".gitignore", ".mk":
+		return commentStyle{line: "# "}, true
+	case ".sql", ".lua", ".hs", ".elm", ".ada":
+		return commentStyle{line: "-- "}, true
+	case ".ini", ".clj", ".cljs", ".lisp", ".scm", ".asm", ".s":
+		return commentStyle{line: "; "}, true
+	case ".tex", ".sty", ".erl", ".hrl":
+		return commentStyle{line: "% "}, true
+	case ".bat", ".cmd":
+		return commentStyle{line: "REM "}, true
+	case ".vim":
+		return commentStyle{line: `" `}, true
+	case ".css":
+		return commentStyle{open: "/* ", done: " */"}, true
+	case ".html", ".htm", ".xhtml", ".xml", ".svg", ".vue", ".svelte", ".md", ".markdown":
+		return commentStyle{open: "<!-- ", done: " -->"}, true
+	}
+	return commentStyle{}, false
+}
+
+// Header returns text as a comment in the language of the file at path,
+// ending in a newline, or "" if the file type has no comment syntax.
+func Header(path, text string) string {
+	style, ok := styleFor(path)
+	text = strings.TrimSpace(text)
+	if !ok || text == "" {
+		return ""
+	}
+	if style.line == "" {
+		return style.open + strings.ReplaceAll(text, style.done, "") + style.done + "\n"
+	}
+	var b strings.Builder
+	for _, l := range strings.Split(text, "\n") {
+		b.WriteString(strings.TrimRight(style.line+strings.TrimSpace(l), " ") + "\n")
+	}
+	return b.String()
+}
+
+// WithHeader puts text at the top of data as a comment (see Header). A
+// shebang, XML declaration or PHP open tag stays on the first line.
+func WithHeader(path, data, text string) string {
+	header := Header(path, text)
+	if header == "" {
+		return data
+	}
+	for _, first := range []string{"#!", "<?xml", "<?php"} {
+		if strings.HasPrefix(data, first) {
+			nl := strings.IndexByte(data, '\n')
+			if nl < 0 {
+				return data + "\n" + header
+			}
+			return data[:nl+1] + header + data[nl+1:]
+		}
+	}
+	return header + data
+}
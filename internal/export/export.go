@@ -0,0 +1,324 @@
+// Package export turns a build plan into a script that recreates the tree on
+// another machine: a shell script, a PowerShell or cmd.exe script, a
+// Dockerfile snippet or a Makefile target.
+//
+// Scripts are idempotent. Directories are created with "mkdir -p" (or its
+// equivalent) and files are only written when they do not exist yet, unless
+// Options.Force is set. File contents are embedded verbatim as heredocs.
+// Symlinks are made only where nothing exists yet, even with Force. The
+// POSIX flavours also set the modes the plan gives with chmod.
+package export
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cytificlabs/tr2rl/internal/fs"
+)
+
+// Shells lists the script flavours Write supports.
+var Shells = []string{"bash", "powershell", "cmd", "dockerfile", "makefile"}
+
+// Options controls the generated script.
+type Options struct {
+	Force bool // overwrite files that already exist when the script runs
+}
+
+// header is the comment every script starts with.
+const header = "Generated by tr2rl. Safe to run more than once"
+
+// Write writes plan as a script for shell. The plan should come from
+// fs.NewEmptyPlan; ops other than mkdir, create and symlink are refused.
+func Write(w io.Writer, plan *fs.Plan, shell string, opts Options) error {
+	for _, op := range plan.Ops {
+		if op.Kind != fs.OpMkdir && op.Kind != fs.OpCreate && op.Kind != fs.OpSymlink {
+			return fmt.Errorf("cannot export %s: %s", op.Path, op.Reason)
+		}
+	}
+
+	var b strings.Builder
+	switch shell {
+	case "bash", "sh":
+		b.WriteString("#!/usr/bin/env bash\n")
+		b.WriteString("# " + header + keepNote(opts) + "\n")
+		writeLines(&b, "", shScript(plan, opts))
+	case "powershell", "pwsh":
+		writePowerShell(&b, plan, opts)
+	case "cmd", "bat":
+		writeCmd(&b, plan, opts)
+	case "dockerfile", "docker":
+		writeDockerfile(&b, plan, opts)
+	case "makefile", "make":
+		writeMakefile(&b, plan, opts)
+	default:
+		return fmt.Errorf("unknown shell %q (want one of: %s)", shell, strings.Join(Shells, ", "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func keepNote(opts Options) string {
+	if opts.Force {
+		return "; existing files are overwritten."
+	}
+	return "; existing files are kept."
+}
+
+func writeLines(b *strings.Builder, prefix string, lines []string) {
+	for _, l := range lines {
+		b.WriteString(prefix + l + "\n")
+	}
+}
+
+// delimiter returns a heredoc terminator that does not occur as a line of text.
+func delimiter(base, text string) string {
+	lines := make(map[string]bool)
+	for _, l := range strings.Split(text, "\n") {
+		lines[strings.TrimSpace(l)] = true
+	}
+	d := base
+	for i := 1; lines[d]; i++ {
+		d = base + "_" + strconv.Itoa(i)
+	}
+	return d
+}
+
+// --- POSIX shell (bash, dockerfile, makefile) ---
+
+// shSafe are the characters a shell word can hold without quoting.
+const shSafe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.,/+@%:="
+
+// shQuote single-quotes s for a POSIX shell when it needs it.
+func shQuote(s string) string {
+	if s != "" && strings.Trim(s, shSafe) == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shScript returns the lines of a POSIX sh script for plan. Contents ending in
+// a newline become quoted heredocs, which the shell never expands; anything
+// else is written with printf so no newline is added.
+func shScript(plan *fs.Plan, opts Options) []string {
+	lines := []string{"set -eu", ""}
+	for _, op := range plan.Ops {
+		p := shQuote(op.Path)
+		switch op.Kind {
+		case fs.OpMkdir:
+			lines = append(lines, "mkdir -p -- "+p)
+			if op.Mode != 0 {
+				lines = append(lines, shChmod(op))
+			}
+			continue
+		case fs.OpSymlink:
+			lines = append(lines, shLink(op))
+			continue
+		}
+
+		var write []string
+		switch {
+		case op.Content == "":
+			write = []string{": > " + p}
+		case strings.HasSuffix(op.Content, "\n"):
+			eof := delimiter("EOF", op.Content)
+			write = append([]string{"cat > " + p + " <<'" + eof + "'"},
+				strings.Split(op.Content+eof, "\n")...)
+		default:
+			write = []string{"printf '%s' " + shQuote(op.Content) + " > " + p}
+		}
+		if op.Mode != 0 {
+			write = append(write, shChmod(op))
+		}
+
+		if opts.Force {
+			lines = append(lines, write...)
+			continue
+		}
+		lines = append(lines, "if [ ! -e "+p+" ]; then")
+		lines = append(lines, write...)
+		lines = append(lines, "fi")
+	}
+	return lines
+}
+
+// shChmod returns the line that gives op.Path the mode of op.
+func shChmod(op fs.Op) string {
+	return fmt.Sprintf("chmod %o -- %s", uint32(op.Mode.Perm()), shQuote(op.Path))
+}
+
+// shLink returns the line that makes the symlink of op unless something,
+// a dangling link included, is already there.
+func shLink(op fs.Op) string {
+	p := shQuote(op.Path)
+	return "[ -e " + p + " ] || [ -L " + p + " ] || ln -s -- " + shQuote(op.Target) + " " + p
+}
+
+// writeDockerfile wraps the shell script in a RUN heredoc (BuildKit, Dockerfile
+// syntax 1.4 and later). Paths are relative to the current WORKDIR.
+func writeDockerfile(b *strings.Builder, plan *fs.Plan, opts Options) {
+	lines := shScript(plan, opts)
+	eof := delimiter("TR2RL", strings.Join(lines, "\n"))
+	b.WriteString("# syntax=docker/dockerfile:1\n")
+	b.WriteString("# " + header + keepNote(opts) + "\n")
+	b.WriteString("# Paths are relative to the WORKDIR.\n")
+	b.WriteString("RUN <<\"" + eof + "\"\n")
+	writeLines(b, "", lines)
+	b.WriteString(eof + "\n")
+}
+
+// printfFormat escapes s as a printf format, so it fits on one line.
+var printfFormat = strings.NewReplacer(`\`, `\\`, "%", "%%", "\n", `\n`, "\r", `\r`)
+
+// writeMakefile writes a "scaffold" target. Make runs every recipe line in
+// its own shell, so files are written with a one-line printf instead of a
+// heredoc. Dollar signs are doubled so make passes them through.
+func writeMakefile(b *strings.Builder, plan *fs.Plan, opts Options) {
+	b.WriteString("# " + header + keepNote(opts) + "\n")
+	b.WriteString("# Run with: make scaffold\n")
+	b.WriteString(".PHONY: scaffold\n")
+	b.WriteString("scaffold:\n")
+	for _, op := range plan.Ops {
+		p := shQuote(op.Path)
+		line := "mkdir -p -- " + p
+		switch op.Kind {
+		case fs.OpSymlink:
+			line = shLink(op)
+		case fs.OpCreate:
+			line = ": > " + p
+			if op.Content != "" {
+				line = "printf " + shQuote(printfFormat.Replace(op.Content)) + " > " + p
+			}
+			if op.Mode != 0 {
+				line = "{ " + line + " && " + shChmod(op) + "; }"
+			}
+			if !opts.Force {
+				line = "[ -e " + p + " ] || " + line
+			}
+		}
+		if op.Kind == fs.OpMkdir && op.Mode != 0 {
+			line += " && " + shChmod(op)
+		}
+		b.WriteString("\t" + strings.ReplaceAll(line, "$", "$$") + "\n")
+	}
+}
+
+// --- PowerShell ---
+
+// psQuote single-quotes s for PowerShell, which also treats the typographic
+// quotes as single quotes.
+func psQuote(s string) string {
+	return "'" + strings.NewReplacer("'", "''", "‘", "‘‘", "’", "’’",
+		"‚", "‚‚", "‛", "‛‛").Replace(s) + "'"
+}
+
+// psString returns s as a PowerShell literal: a verbatim here-string when s has
+// several lines, a quoted string otherwise.
+func psString(s string) string {
+	if !strings.Contains(s, "\n") || strings.Contains("\n"+s, "\n'@") {
+		return psQuote(s)
+	}
+	// The newline before the closing '@ is not part of the value.
+	return "@'\n" + s + "\n'@"
+}
+
+func writePowerShell(b *strings.Builder, plan *fs.Plan, opts Options) {
+	b.WriteString("# " + header + keepNote(opts) + "\n")
+	b.WriteString("$ErrorActionPreference = 'Stop'\n\n")
+	dirs := make(map[string]bool)
+	for _, op := range plan.Ops {
+		p := psQuote(op.Path)
+		switch op.Kind {
+		case fs.OpMkdir:
+			dirs[op.Path] = true
+			fmt.Fprintf(b, "New-Item -ItemType Directory -Force -Path %s | Out-Null\n", p)
+			continue
+		case fs.OpSymlink:
+			// Windows PowerShell resolves a relative New-Item -Target against the
+			// current location instead of the link's directory, so the link is
+			// made by mklink, as in the cmd flavour. It needs Developer Mode or
+			// an elevated shell, and must be told when the target is a directory.
+			flag := ""
+			if dirs[path.Join(path.Dir(op.Path), op.Target)] {
+				flag = "/D "
+			}
+			// A trailing backslash would escape the quote PowerShell adds.
+			target := strings.TrimRight(strings.ReplaceAll(op.Target, "/", `\`), `\`)
+			fmt.Fprintf(b, "if (-not (Test-Path -LiteralPath %s)) {\n", p)
+			fmt.Fprintf(b, "    cmd /c mklink %s%s %s | Out-Null\n", flag, psQuote(strings.ReplaceAll(op.Path, "/", `\`)), psQuote(target))
+			fmt.Fprintf(b, "    if ($LASTEXITCODE) { throw %s }\n", psQuote("mklink failed for "+op.Path))
+			b.WriteString("}\n")
+			continue
+		}
+
+		// WriteAllText writes UTF-8 without a BOM and without adding a newline;
+		// it needs a full path because .NET does not follow Set-Location.
+		write := fmt.Sprintf("    New-Item -ItemType File -Force -Path %s | Out-Null", p)
+		if op.Content != "" {
+			write = fmt.Sprintf("    [IO.File]::WriteAllText((Join-Path $PWD.ProviderPath %s), %s)", p, psString(op.Content))
+		}
+		if opts.Force {
+			b.WriteString(strings.TrimPrefix(write, "    ") + "\n")
+			continue
+		}
+		fmt.Fprintf(b, "if (-not (Test-Path -LiteralPath %s)) {\n", p)
+		b.WriteString(write + "\n")
+		b.WriteString("}\n")
+	}
+}
+
+// --- cmd.exe ---
+
+// cmdQuote double-quotes a path for a batch file. Windows names cannot hold
+// double quotes; percent signs are doubled so they are not expanded.
+func cmdQuote(p string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(p, "/", `\`), "%", "%%") + `"`
+}
+
+// cmdEcho escapes a line for "echo(" in a batch file.
+var cmdEcho = strings.NewReplacer("%", "%%", "^", "^^", "&", "^&", "|", "^|", "<", "^<", ">", "^>",
+	`"`, `^"`, "(", "^(", ")", "^)")
+
+// writeCmd writes a batch file. cmd.exe has no heredocs, so each line of a
+// file is appended with its own echo, and every file ends with a newline.
+func writeCmd(b *strings.Builder, plan *fs.Plan, opts Options) {
+	b.WriteString("@echo off\n")
+	b.WriteString("rem " + header + keepNote(opts) + "\n")
+	b.WriteString("setlocal EnableExtensions DisableDelayedExpansion\n\n")
+	dirs := make(map[string]bool)
+	for i, op := range plan.Ops {
+		p := cmdQuote(op.Path)
+		switch op.Kind {
+		case fs.OpMkdir:
+			dirs[op.Path] = true
+			fmt.Fprintf(b, "if not exist %s mkdir %s\n", strings.TrimSuffix(p, `"`)+`\"`, p)
+			continue
+		case fs.OpSymlink:
+			// mklink must be told when the target is a directory.
+			flag := ""
+			if dirs[path.Join(path.Dir(op.Path), op.Target)] {
+				flag = "/D "
+			}
+			fmt.Fprintf(b, "if not exist %s mklink %s%s %s >nul\n", p, flag, p, cmdQuote(op.Target))
+			continue
+		}
+
+		skip := fmt.Sprintf("skip%d", i)
+		if !opts.Force {
+			fmt.Fprintf(b, "if exist %s goto %s\n", p, skip)
+		}
+		fmt.Fprintf(b, "type nul > %s\n", p)
+		if op.Content != "" {
+			text := strings.TrimSuffix(strings.ReplaceAll(op.Content, "\r\n", "\n"), "\n")
+			for _, l := range strings.Split(text, "\n") {
+				fmt.Fprintf(b, ">>%s echo(%s\n", p, cmdEcho.Replace(l))
+			}
+		}
+		if !opts.Force {
+			fmt.Fprintf(b, ":%s\n", skip)
+		}
+	}
+	b.WriteString("endlocal\n")
+}
@@ -0,0 +1,210 @@
+package export
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/internal/fs"
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+// tricky covers names and contents that break naive quoting.
+var tricky = []parser.Node{
+	{Path: "my app", Kind: parser.Dir},
+	{Path: "my app/it's $HOME.md", Kind: parser.File, Content: "# $HOME `pwd` \\n 100%\nEOF\n\tindented\n"},
+	{Path: "my app/no-newline.txt", Kind: parser.File, Content: "a 'quoted'\nlast line"},
+	{Path: "my app/empty", Kind: parser.File},
+	{Path: "src/main.go", Kind: parser.File, Content: "package main\n"},
+	{Path: "bin", Kind: parser.Dir, Mode: 0700},
+	{Path: "bin/run.sh", Kind: parser.File, Content: "#!/bin/sh\n", Mode: 0755},
+	{Path: "my app/latest", Kind: parser.Symlink, Target: "no-newline.txt"},
+	{Path: "app", Kind: parser.Symlink, Target: "my app"},
+}
+
+func script(t *testing.T, shell string, opts Options) string {
+	t.Helper()
+	var b strings.Builder
+	if err := Write(&b, fs.NewEmptyPlan(tricky, fs.ApplyOptions{}), shell, opts); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// runScript runs a script twice in a fresh directory and returns the files it
+// made, keyed by slash path.
+func runScript(t *testing.T, name string, args ...string) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ { // the second run must be a no-op
+		cmd := exec.Command(name, args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s failed: %v\n%s", name, err, out)
+		}
+	}
+	return readTree(t, dir)
+}
+
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	got := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || p == dir || d.Name() == "scaffold.sh" || d.Name() == "Makefile" {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		if d.Type()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(p)
+			got[filepath.ToSlash(rel)] = "-> " + target
+			return err
+		}
+		if d.IsDir() {
+			got[filepath.ToSlash(rel)+"/"] = ""
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		got[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func wantTree(t *testing.T) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := fs.Apply(dir, tricky, fs.ApplyOptions{NoJournal: true}); err != nil {
+		t.Fatal(err)
+	}
+	return readTree(t, dir)
+}
+
+func compareTrees(t *testing.T, got, want map[string]string) {
+	t.Helper()
+	for p, w := range want {
+		if g, ok := got[p]; !ok {
+			t.Errorf("missing %s", p)
+		} else if g != w {
+			t.Errorf("%s = %q, want %q", p, g, w)
+		}
+	}
+	for p := range got {
+		if _, ok := want[p]; !ok {
+			t.Errorf("unexpected %s", p)
+		}
+	}
+}
+
+func TestBashMatchesApply(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	path := filepath.Join(t.TempDir(), "scaffold.sh")
+	if err := os.WriteFile(path, []byte(script(t, "bash", Options{})), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	compareTrees(t, runScript(t, "bash", path), wantTree(t))
+}
+
+func TestMakefileMatchesApply(t *testing.T) {
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not installed")
+	}
+	path := filepath.Join(t.TempDir(), "Makefile")
+	if err := os.WriteFile(path, []byte(script(t, "makefile", Options{})), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	compareTrees(t, runScript(t, "make", "-s", "-f", path, "scaffold"), wantTree(t))
+}
+
+func TestExistingFilesAreKept(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src"), 0o755)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("mine\n"), 0o644)
+
+	for _, force := range []bool{false, true} {
+		cmd := exec.Command("bash", "-c", script(t, "bash", Options{Force: force}))
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("bash failed: %v\n%s", err, out)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, "src", "main.go"))
+		want := "mine\n"
+		if force {
+			want = "package main\n"
+		}
+		if string(data) != want {
+			t.Errorf("force=%v: main.go = %q, want %q", force, data, want)
+		}
+	}
+}
+
+func TestScripts(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{"bash", []string{
+			"mkdir -p -- 'my app'\n",
+			"if [ ! -e 'my app/it'\\''s $HOME.md' ]; then\n",
+			"<<'EOF_1'\n",
+			"printf '%s' 'a '\\''quoted'\\''\nlast line' > 'my app/no-newline.txt'\n",
+			"[ -e 'my app/latest' ] || [ -L 'my app/latest' ] || ln -s -- no-newline.txt 'my app/latest'\n",
+			"mkdir -p -- bin\nchmod 700 -- bin\n",
+			"EOF\nchmod 755 -- bin/run.sh\nfi\n",
+		}},
+		{"powershell", []string{
+			"New-Item -ItemType Directory -Force -Path 'my app' | Out-Null\n",
+			"if (-not (Test-Path -LiteralPath 'my app/it''s $HOME.md')) {\n",
+			", @'\n# $HOME",
+			"\tindented\n\n'@)\n",
+			"@'\na 'quoted'\nlast line\n'@)\n",
+			"    cmd /c mklink 'my app\\latest' 'no-newline.txt' | Out-Null\n",
+			"    cmd /c mklink /D 'app' 'my app' | Out-Null\n    if ($LASTEXITCODE) { throw 'mklink failed for app' }\n",
+		}},
+		{"cmd", []string{
+			"if not exist \"my app\\\" mkdir \"my app\"\n",
+			"if exist \"my app\\empty\" goto skip",
+			">>\"my app\\it's $HOME.md\" echo(# $HOME `pwd` \\n 100%%\n",
+			"if not exist \"my app\\latest\" mklink \"my app\\latest\" \"no-newline.txt\" >nul\n",
+			"if not exist \"app\" mklink /D \"app\" \"my app\" >nul\n",
+		}},
+		{"dockerfile", []string{
+			"# syntax=docker/dockerfile:1\n",
+			"RUN <<\"TR2RL\"\nset -eu\n",
+			"EOF_1\nfi\n",
+			"\nTR2RL\n",
+		}},
+		{"makefile", []string{
+			"scaffold:\n\tmkdir -p -- 'my app'\n",
+			"printf '# $$HOME `pwd` \\\\n 100%%\\nEOF\\n\tindented\\n'",
+			"\tmkdir -p -- bin && chmod 700 -- bin\n",
+			"\t[ -e bin/run.sh ] || { printf '#!/bin/sh\\n' > bin/run.sh && chmod 755 -- bin/run.sh; }\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			got := script(t, tt.shell, Options{})
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("missing %q in:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownShell(t *testing.T) {
+	err := Write(&strings.Builder{}, &fs.Plan{}, "fish", Options{})
+	if err == nil || !strings.Contains(err.Error(), "bash, powershell") {
+		t.Errorf("err = %v", err)
+	}
+}
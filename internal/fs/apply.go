@@ -1,70 +1,190 @@
 // Package fs handles the actual filesystem operations for tr2rl.
 // It includes safety features like dry-run mode and overwrite protection
 // to ensure users don't accidentally destroy their work.
+//
+// Building is split in two steps: NewPlan inspects the filesystem and decides
+// what to do, Execute carries a plan out. Apply runs both.
+//
+// Every build goes through the Dest and FS interfaces: DirFS for the local
+// disk, MemFS (or an overlay over any read-only fs.FS) for memory, and
+// archive writers. ApplyTo and ExecuteTo take any of them; rollback and the
+// journal work wherever the destination supports them. Dry runs use an
+// overlay: the plan is run in memory and the changes are reported.
 package fs
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"path"
 
-	"github.com/cytificlabs/tr2rl/internal/content"
 	"github.com/cytificlabs/tr2rl/internal/parser"
 )
 
 type ApplyOptions struct {
-	DryRun   bool
-	Force    bool
-	Populate bool
+	DryRun     bool
+	Force      bool
+	Populate   bool
+	NoRollback bool // keep partial results when a build fails halfway
+	NoJournal  bool // do not record the build under .tr2rl/journal
+
+	// AllowOutside permits paths that resolve outside rootDir (via ".." or an
+	// existing symlink). Off by default so untrusted trees cannot escape.
+	AllowOutside bool
+}
+
+// ExecuteOptions controls how a plan is carried out.
+type ExecuteOptions struct {
+	Log        io.Writer // progress lines ("[OK] Created ..."); nil discards them
+	NoRollback bool      // by default a failed build undoes everything it changed
+	NoJournal  bool      // by default every build is journaled so 'tr2rl undo' can reverse it
+
+	// AllowOutside skips the check that every op stays inside Plan.Root.
+	// Plans are plain JSON, so they are re-checked before anything runs.
+	AllowOutside bool
+
+	// Context is checked before every operation; a cancelled build fails
+	// (and is rolled back like any other). Nil never cancels.
+	Context context.Context
 }
 
 // Apply materializes the parsed nodes into the filesystem at rootDir.
 func Apply(rootDir string, nodes []parser.Node, opts ApplyOptions) error {
-	for _, node := range nodes {
-		fullPath := filepath.Join(rootDir, node.Path)
-
-		if opts.DryRun {
-			// In dry-run, just print what we would do
-			suffix := ""
-			if node.Kind == parser.Dir {
-				suffix = "/"
+	plan, err := NewPlan(rootDir, nodes, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		return plan.Preview(os.Stdout, os.DirFS(rootDir))
+	}
+	return plan.Execute(ExecuteOptions{
+		Log:          os.Stdout,
+		NoRollback:   opts.NoRollback,
+		NoJournal:    opts.NoJournal,
+		AllowOutside: opts.AllowOutside,
+	})
+}
+
+// Execute carries out the plan in the directory Plan.Root; see ExecuteTo.
+func (p *Plan) Execute(opts ExecuteOptions) error {
+	return p.ExecuteTo(DirFS(p.Root), opts)
+}
+
+// ExecuteTo carries out the plan into dst. It refuses to run a plan with
+// conflicts, and fails if a file it is supposed to create has appeared since
+// the plan was made.
+//
+// Builds are atomic: every directory and file created, and every file
+// overwritten, is recorded. If any step fails, the created paths are removed
+// and the overwritten files restored before the error is returned; a Dest
+// that cannot remove files, such as an archive, is left for the caller to
+// throw away. The record of a build into an FS that went through (or failed
+// with NoRollback) is saved as a journal under .tr2rl so Undo can reverse it
+// later.
+func (p *Plan) ExecuteTo(dst Dest, opts ExecuteOptions) error {
+	w := opts.Log
+	if w == nil {
+		w = io.Discard
+	}
+	if n := p.Count(OpConflict); n > 0 {
+		for _, op := range p.Ops {
+			if op.Kind == OpConflict {
+				fmt.Fprintf(w, "[CONFLICT] %s: %s\n", op.Path, op.Reason)
 			}
-			fmt.Printf("[DRY-RUN] Create %s%s\n", fullPath, suffix)
-			continue
 		}
-
-		if node.Kind == parser.Dir {
-			if err := os.MkdirAll(fullPath, 0755); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", fullPath, err)
+		return fmt.Errorf("plan has %d conflict(s); nothing was written", n)
+	}
+	if !opts.AllowOutside {
+		check := pathCheck(dst)
+		for _, op := range p.Ops {
+			err := check(op.Path)
+			if err == nil && op.Kind == OpSymlink {
+				err = checkLink(op.Path, op.Target, check)
+			}
+			if err != nil {
+				return fmt.Errorf("refusing to run plan: %w", err)
 			}
+		}
+	}
+
+	t := &txn{dst: dst}
+	err := p.execute(opts.Context, t, w)
+	if err != nil && !opts.NoRollback && len(t.changes) > 0 && t.canRollback() {
+		if rerr := t.rollback(w); rerr != nil {
+			return fmt.Errorf("%w (rollback incomplete: %v)", err, rerr)
+		}
+		return fmt.Errorf("%w (all %d change(s) were rolled back)", err, len(t.changes))
+	}
+
+	fsys, ok := dst.(FS)
+	if ok && !opts.NoJournal && len(t.changes) > 0 {
+		id, jerr := writeJournal(fsys, t, opts.AllowOutside)
+		if jerr != nil {
+			fmt.Fprintf(w, "[WARN] Could not write build journal: %v\n", jerr)
 		} else {
-			// Ensure parent dir exists
-			parent := filepath.Dir(fullPath)
-			if err := os.MkdirAll(parent, 0755); err != nil {
-				return fmt.Errorf("failed to create parent dir %s: %w", parent, err)
+			fmt.Fprintf(w, "Build recorded as %s (reverse it with 'tr2rl undo')\n", id)
+		}
+	}
+	return err
+}
+
+func (p *Plan) execute(ctx context.Context, t *txn, w io.Writer) error {
+	for _, op := range p.Ops {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		name := path.Clean(op.Path)
+
+		switch op.Kind {
+		case OpMkdir:
+			if err := t.mkdirAll(name, op.Mode); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", op.Path, err)
 			}
+			fmt.Fprintf(w, "[OK] Created %s/\n", op.Path)
 
-			// Check if file exists
-			if _, err := os.Stat(fullPath); err == nil {
-				if !opts.Force {
-					fmt.Printf("[SKIP] File exists: %s (use --force to overwrite)\n", node.Path)
-					continue
-				}
+		case OpCreate:
+			if err := t.mkdirParents(name); err != nil {
+				return fmt.Errorf("failed to create parent dir %s: %w", path.Dir(name), err)
+			}
+			if err := t.createFile(name, []byte(op.Content), op.Mode); err != nil {
+				return fmt.Errorf("failed to create file %s: %w", op.Path, err)
 			}
+			fmt.Fprintf(w, "[OK] Created %s\n", op.Path)
 
-			// Prepare content
-			data := ""
-			if opts.Populate {
-				data = content.GetContent(fullPath)
+		case OpOverwrite:
+			if err := t.overwriteFile(name, []byte(op.Content), op.Mode); err != nil {
+				return fmt.Errorf("failed to overwrite file %s: %w", op.Path, err)
 			}
+			fmt.Fprintf(w, "[OK] Overwrote %s\n", op.Path)
 
-			// Create/Truncate file
-			// Use os.WriteFile for simplicity
-			if err := os.WriteFile(fullPath, []byte(data), 0644); err != nil {
-				return fmt.Errorf("failed to create file %s: %w", fullPath, err)
+		case OpSymlink:
+			if err := t.mkdirParents(name); err != nil {
+				return fmt.Errorf("failed to create parent dir %s: %w", path.Dir(name), err)
 			}
-			fmt.Printf("[OK] Created %s\n", node.Path)
+			if err := t.createSymlink(op.Target, name); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", op.Path, err)
+			}
+			fmt.Fprintf(w, "[OK] Linked %s -> %s\n", op.Path, op.Target)
+
+		case OpSkipExists:
+			logSkip(w, op)
+
+		default:
+			return fmt.Errorf("unknown plan operation %q for %s", op.Kind, op.Path)
 		}
 	}
 	return nil
 }
+
+// logSkip reports a path the plan leaves alone.
+func logSkip(w io.Writer, op Op) {
+	if op.Target != "" {
+		fmt.Fprintf(w, "[SKIP] Path exists: %s (symlinks never replace existing paths)\n", op.Path)
+		return
+	}
+	fmt.Fprintf(w, "[SKIP] File exists: %s (use --force to overwrite)\n", op.Path)
+}
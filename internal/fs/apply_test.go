@@ -1,10 +1,14 @@
 package fs
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/cytificlabs/tr2rl/internal/content"
 	"github.com/cytificlabs/tr2rl/internal/parser"
 )
 
@@ -76,3 +80,309 @@ func TestApply_Integration(t *testing.T) {
 		t.Error("Apply with --force did not truncate file")
 	}
 }
+
+func TestApply_NodeContent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	nodes := []parser.Node{
+		{Path: "main.go", Kind: parser.File, Content: "package main\n\nfunc main() {}\n"},
+		{Path: "util/util.go", Kind: parser.File},
+		{Path: "build/Containerfile", Kind: parser.File, Template: "Dockerfile"},
+	}
+
+	if err := Apply(tmpDir, nodes, ApplyOptions{Populate: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	main, _ := os.ReadFile(filepath.Join(tmpDir, "main.go"))
+	if string(main) != nodes[0].Content {
+		t.Errorf("spec content should win over boilerplate, got %q", main)
+	}
+	util, _ := os.ReadFile(filepath.Join(tmpDir, "util", "util.go"))
+	if string(util) != "package util\n" {
+		t.Errorf("files without content should fall back to --populate, got %q", util)
+	}
+	containerfile, _ := os.ReadFile(filepath.Join(tmpDir, "build", "Containerfile"))
+	if !strings.HasPrefix(string(containerfile), "FROM ") {
+		t.Errorf("template should pick the Dockerfile boilerplate, got %q", containerfile)
+	}
+}
+
+func TestApply_DescriptionHeader(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	nodes := []parser.Node{
+		{Path: "main.go", Kind: parser.File, Description: "entry point"},
+		{Path: "deploy.sh", Kind: parser.File, Description: "ships it"},
+		{Path: "data.json", Kind: parser.File, Description: "no comments in JSON"},
+		{Path: "given.go", Kind: parser.File, Content: "package given\n", Description: "kept as is"},
+	}
+
+	if err := Apply(tmpDir, nodes, ApplyOptions{Populate: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(tmpDir, name))
+		return string(data)
+	}
+	if got := read("main.go"); !strings.HasPrefix(got, "// entry point\npackage main") {
+		t.Errorf("main.go should start with the description, got %q", got)
+	}
+	if got := read("deploy.sh"); !strings.HasPrefix(got, "#!") || !strings.Contains(got, "\n# ships it\n") {
+		t.Errorf("deploy.sh should keep its shebang first, got %q", got)
+	}
+	if got := read("data.json"); strings.Contains(got, "no comments") {
+		t.Errorf("data.json has no comment syntax, got %q", got)
+	}
+	if got := read("given.go"); got != "package given\n" {
+		t.Errorf("spec content should be written unchanged, got %q", got)
+	}
+}
+
+func TestApply_Symlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "taken"), []byte("mine"), 0644)
+
+	nodes := []parser.Node{
+		{Path: "shared/config.yml", Kind: parser.File, Content: "a: 1\n"},
+		{Path: "app/config.yml", Kind: parser.Symlink, Target: "../shared/config.yml"},
+		{Path: "current", Kind: parser.Symlink, Target: "shared"},
+		{Path: "taken", Kind: parser.Symlink, Target: "shared"},
+	}
+	if err := Apply(tmpDir, nodes, ApplyOptions{NoJournal: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if target, err := os.Readlink(filepath.Join(tmpDir, "app", "config.yml")); err != nil || target != filepath.FromSlash("../shared/config.yml") {
+		t.Errorf("app/config.yml -> %q (err %v)", target, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "current", "config.yml")); string(data) != "a: 1\n" {
+		t.Errorf("reading through the directory link got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "taken")); string(data) != "mine" {
+		t.Errorf("a symlink must not replace an existing file, got %q", data)
+	}
+
+	for _, target := range []string{"../../etc/passwd", "/etc/passwd"} {
+		escape := []parser.Node{{Path: "app/evil", Kind: parser.Symlink, Target: target}}
+		plan, err := NewPlan(tmpDir, escape, ApplyOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plan.Count(OpConflict) != 1 {
+			t.Errorf("target %q should be a conflict, got %+v", target, plan.Ops)
+		}
+		if plan, _ = NewPlan(tmpDir, escape, ApplyOptions{AllowOutside: true}); plan.Count(OpSymlink) != 1 {
+			t.Errorf("AllowOutside should permit target %q, got %+v", target, plan.Ops)
+		}
+	}
+
+	// A symlink cannot also be a directory of the spec.
+	plan := NewEmptyPlan([]parser.Node{
+		{Path: "lib", Kind: parser.Symlink, Target: "vendor"},
+		{Path: "lib/x.go", Kind: parser.File},
+	}, ApplyOptions{})
+	if plan.Count(OpConflict) != 1 {
+		t.Errorf("a file below a symlink should be a conflict, got %+v", plan.Ops)
+	}
+}
+
+func TestApply_Modes(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	nodes := []parser.Node{
+		{Path: "secrets", Kind: parser.Dir, Mode: 0700},
+		{Path: "secrets/key.pem", Kind: parser.File, Mode: 0600},
+		{Path: "scripts/deploy.sh", Kind: parser.File},
+		{Path: "scripts/given.sh", Kind: parser.File, Content: "#!/bin/sh\n"},
+		{Path: "scripts/group.sh", Kind: parser.File, Mode: 0775},
+		{Path: "README.md", Kind: parser.File},
+	}
+	if err := Apply(tmpDir, nodes, ApplyOptions{Populate: true, NoJournal: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]os.FileMode{
+		"secrets":           0700,
+		"secrets/key.pem":   0600,
+		"scripts/deploy.sh": ScriptMode, // populated with a shebang
+		"scripts/group.sh":  0775,       // exactly, whatever the umask
+	}
+	for p, mode := range want {
+		info, err := os.Stat(filepath.Join(tmpDir, p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("%s: mode %o, want %o", p, info.Mode().Perm(), mode)
+		}
+	}
+	for _, p := range []string{"scripts/given.sh", "README.md"} {
+		if info, _ := os.Stat(filepath.Join(tmpDir, p)); info.Mode()&0111 != 0 {
+			t.Errorf("%s should not be executable (content from the spec, or no shebang), got %o", p, info.Mode().Perm())
+		}
+	}
+}
+
+func TestPlan_FromFilesystemState(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "src"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "src", "main.go"), []byte("keep"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "docs"), nil, 0644)
+
+	nodes := []parser.Node{
+		{Path: "src", Kind: parser.Dir},
+		{Path: "src/main.go", Kind: parser.File},
+		{Path: "src/pkg/util.go", Kind: parser.File},
+		{Path: "docs/guide.md", Kind: parser.File},
+	}
+
+	plan, err := NewPlan(tmpDir, nodes, ApplyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Op{
+		{Kind: OpSkipExists, Path: "src/main.go"},
+		{Kind: OpMkdir, Path: "src/pkg"},
+		{Kind: OpCreate, Path: "src/pkg/util.go"},
+		{Kind: OpConflict, Path: "docs"},
+	}
+	if len(plan.Ops) != len(expected) {
+		t.Fatalf("expected %d ops, got %+v", len(expected), plan.Ops)
+	}
+	for i, op := range plan.Ops {
+		if op.Kind != expected[i].Kind || op.Path != expected[i].Path {
+			t.Errorf("op %d: expected %s %s, got %s %s", i, expected[i].Kind, expected[i].Path, op.Kind, op.Path)
+		}
+	}
+
+	if err := plan.Execute(ExecuteOptions{}); err == nil {
+		t.Error("a plan with conflicts must not execute")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "src", "pkg")); !os.IsNotExist(err) {
+		t.Error("refused plan still touched the disk")
+	}
+}
+
+func TestPlan_SaveAndExecute(t *testing.T) {
+	tmpDir := t.TempDir()
+	nodes := []parser.Node{{Path: "a/b.txt", Kind: parser.File, Content: "hello\n"}}
+
+	plan, err := NewPlan(tmpDir, nodes, ApplyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePlan(&buf, plan); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadPlan(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Execute(ExecuteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(tmpDir, "a", "b.txt"))
+	if string(data) != "hello\n" {
+		t.Errorf("expected plan content to be written, got %q", data)
+	}
+
+	// The file now exists, so re-running the same (stale) plan must fail.
+	if err := loaded.Execute(ExecuteOptions{}); err == nil {
+		t.Error("stale create op overwrote an existing file")
+	}
+}
+
+func TestExecute_RollbackOnFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "existing.txt"), []byte("original"), 0600)
+	os.WriteFile(filepath.Join(tmpDir, "late.txt"), []byte("appeared after planning"), 0644)
+
+	plan := &Plan{
+		Version: PlanVersion,
+		Root:    tmpDir,
+		Ops: []Op{
+			{Kind: OpMkdir, Path: "new/deep"},
+			{Kind: OpCreate, Path: "new/deep/a.txt", Content: "a"},
+			{Kind: OpOverwrite, Path: "existing.txt", Content: "replaced"},
+			{Kind: OpCreate, Path: "late.txt", Content: "boom"}, // fails: file exists
+		},
+	}
+
+	err := plan.Execute(ExecuteOptions{})
+	if err == nil {
+		t.Fatal("expected the stale create to fail")
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "new")); !os.IsNotExist(err) {
+		t.Error("rollback left the created directory behind")
+	}
+	data, _ := os.ReadFile(filepath.Join(tmpDir, "existing.txt"))
+	if string(data) != "original" {
+		t.Errorf("rollback did not restore the overwritten file, got %q", data)
+	}
+	if info, _ := os.Stat(filepath.Join(tmpDir, "existing.txt")); info.Mode().Perm() != 0600 {
+		t.Errorf("rollback did not restore the file mode, got %v", info.Mode().Perm())
+	}
+	data, _ = os.ReadFile(filepath.Join(tmpDir, "late.txt"))
+	if string(data) != "appeared after planning" {
+		t.Error("rollback touched a file it did not create")
+	}
+
+	// With rollback disabled the partial result stays.
+	plan.Execute(ExecuteOptions{NoRollback: true})
+	if _, err := os.Stat(filepath.Join(tmpDir, "new", "deep", "a.txt")); err != nil {
+		t.Error("--no-rollback should keep partial results")
+	}
+}
+
+// memDest records what a build writes, for ApplyTo.
+type memDest map[string]string
+
+func (m memDest) Mkdir(name string, perm os.FileMode) error {
+	m[name+"/"] = fmt.Sprintf("%o", perm)
+	return nil
+}
+
+func (m memDest) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m[name] = fmt.Sprintf("%o %s", perm, data)
+	return nil
+}
+
+func TestApplyTo(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "scripts/run.sh", Kind: parser.File, Content: "#!/bin/sh\n", Mode: 0755},
+		{Path: "go.mod", Kind: parser.File},
+		{Path: "docs", Kind: parser.Dir},
+	}
+	dst := memDest{}
+	if err := ApplyTo(dst, nodes, ApplyOptions{Populate: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := memDest{
+		"scripts/":       "755",
+		"scripts/run.sh": "755 #!/bin/sh\n",
+		"go.mod":         "644 " + content.GetContent("go.mod"),
+		"docs/":          "755",
+	}
+	if len(dst) != len(want) {
+		t.Fatalf("got %v, want %v", dst, want)
+	}
+	for k, v := range want {
+		if dst[k] != v {
+			t.Errorf("%s = %q, want %q", k, dst[k], v)
+		}
+	}
+
+	// Paths leaving the root are conflicts, and nothing is written.
+	dst = memDest{}
+	err := ApplyTo(dst, []parser.Node{{Path: "a.txt", Kind: parser.File}, {Path: "../evil", Kind: parser.File}}, ApplyOptions{})
+	if err == nil || len(dst) != 0 {
+		t.Errorf("escaping path: err = %v, wrote %v", err, dst)
+	}
+}
@@ -0,0 +1,223 @@
+package fs
+
+import (
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+// Dest receives a build: a directory on disk (DirFS), memory (MemFS) or an
+// archive stream. Names are slash paths relative to the root of the
+// build, and a directory is always made before anything inside it.
+type Dest interface {
+	Mkdir(name string, perm os.FileMode) error
+	WriteFile(name string, data []byte, perm os.FileMode) error
+}
+
+// FS is a Dest that can also be looked at, so a build into it is planned
+// against what it already holds: existing files are skipped (or overwritten
+// with Force) and a file in the way of a directory is a conflict. MemFS and
+// DirFS implement it.
+type FS interface {
+	Dest
+	Stat(name string) (iofs.FileInfo, error)
+}
+
+// Linker is a Dest that can hold symbolic links. A plan with symlinks can
+// only be carried out into a Linker; DirFS, MemFS and archives are ones.
+type Linker interface {
+	Symlink(target, name string) error
+}
+
+// Optional methods of a Dest that a build makes use of when they are there.
+type (
+	// lstater describes a symlink itself rather than what it points at.
+	// Without it, planning a symlink falls back to Stat.
+	lstater interface {
+		Lstat(name string) (iofs.FileInfo, error)
+	}
+	// reader lets an overwritten file be backed up, for rollback and undo.
+	reader interface {
+		ReadFile(name string) ([]byte, error)
+	}
+	// remover lets a failed build be rolled back.
+	remover interface {
+		Remove(name string) error
+	}
+	// chmoder sets exact permissions where a umask narrows them.
+	chmoder interface {
+		Chmod(name string, mode os.FileMode) error
+	}
+	// fileCreator writes a file only if it does not exist yet, atomically.
+	// Without it, a create checks with Stat first.
+	fileCreator interface {
+		CreateFile(name string, data []byte, perm os.FileMode) error
+	}
+)
+
+// DirFS returns the FS for a directory on disk. Apply and Execute build
+// through it; the root is created when it does not exist yet.
+func DirFS(root string) FS {
+	return dirFS(root)
+}
+
+type dirFS string
+
+func (d dirFS) Stat(name string) (iofs.FileInfo, error) {
+	return os.Stat(targetPath(string(d), name))
+}
+
+func (d dirFS) Lstat(name string) (iofs.FileInfo, error) {
+	return os.Lstat(targetPath(string(d), name))
+}
+
+func (d dirFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(targetPath(string(d), name))
+}
+
+func (d dirFS) Symlink(target, name string) error {
+	return os.Symlink(filepath.FromSlash(target), targetPath(string(d), name))
+}
+
+func (d dirFS) Mkdir(name string, perm os.FileMode) error {
+	if path.Clean(name) == "." {
+		return os.MkdirAll(string(d), perm) // the root may be several levels deep
+	}
+	return os.Mkdir(targetPath(string(d), name), perm)
+}
+
+func (d dirFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(targetPath(string(d), name), data, perm)
+}
+
+func (d dirFS) CreateFile(name string, data []byte, perm os.FileMode) error {
+	file := targetPath(string(d), name)
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file)
+	}
+	return err
+}
+
+func (d dirFS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(targetPath(string(d), name), mode)
+}
+
+func (d dirFS) Remove(name string) error {
+	return os.Remove(targetPath(string(d), name))
+}
+
+// pathCheck returns the check that keeps paths inside the root of fsys:
+// through existing symlinks on disk, lexically anywhere else.
+func pathCheck(fsys any) func(rel string) error {
+	if d, ok := fsys.(dirFS); ok {
+		return func(rel string) error { return checkPath(string(d), rel) }
+	}
+	return checkRel
+}
+
+// NewPlanFS is NewPlan for an FS: it decides what a build into fsys would do.
+func NewPlanFS(fsys FS, nodes []parser.Node, opts ApplyOptions) (*Plan, error) {
+	lstat := fsys.Stat
+	if l, ok := fsys.(lstater); ok {
+		lstat = l.Lstat
+	}
+	return newPlan("", nodes, opts, fsys.Stat, lstat, pathCheck(fsys))
+}
+
+// Permissions used when the spec gives none; the same as a build on disk.
+// ScriptMode is for generated files that start with a "#!" line.
+const (
+	DefaultDirMode  os.FileMode = 0755
+	DefaultFileMode os.FileMode = 0644
+	ScriptMode      os.FileMode = 0755
+)
+
+// ApplyTo builds nodes into dst. It plans like Apply (populate, templates,
+// path checks) and refuses a plan with conflicts. A plain Dest is treated as
+// empty; an FS is planned against its contents. With DryRun the plan is
+// printed and dst is left alone.
+func ApplyTo(dst Dest, nodes []parser.Node, opts ApplyOptions) error {
+	plan := NewEmptyPlan(nodes, opts)
+	if fsys, ok := dst.(FS); ok {
+		var err error
+		if plan, err = NewPlanFS(fsys, nodes, opts); err != nil {
+			return err
+		}
+	}
+	if opts.DryRun {
+		plan.Print(os.Stdout)
+		return nil
+	}
+	return plan.ExecuteTo(dst, ExecuteOptions{
+		Log:          os.Stdout,
+		NoRollback:   opts.NoRollback,
+		NoJournal:    opts.NoJournal,
+		AllowOutside: opts.AllowOutside,
+	})
+}
+
+func modeOr(mode, def os.FileMode) os.FileMode {
+	if mode == 0 {
+		return def
+	}
+	return mode
+}
+
+// Preview runs the plan against an in-memory overlay of base, so nothing is
+// written, and prints the resulting changes in the [DRY-RUN] format. Skipped
+// and conflicting paths are listed in plan order along with them.
+func (p *Plan) Preview(w io.Writer, base iofs.FS) error {
+	ov := NewOverlay(base)
+	for _, op := range p.Ops {
+		var err error
+		switch op.Kind {
+		case OpMkdir:
+			err = ov.Mkdir(op.Path, modeOr(op.Mode, DefaultDirMode))
+		case OpCreate, OpOverwrite:
+			err = ov.WriteFile(op.Path, []byte(op.Content), modeOr(op.Mode, DefaultFileMode))
+		case OpSymlink:
+			err = ov.Symlink(op.Target, op.Path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	changes := make(map[string]Change)
+	for _, c := range ov.Changes() {
+		changes[c.Path] = c
+	}
+	for _, op := range p.Ops {
+		c, changed := changes[path.Clean(op.Path)]
+		switch {
+		case op.Kind == OpSkipExists:
+			fmt.Fprintf(w, "[DRY-RUN] Skip %s: %s\n", op.Path, op.Reason)
+		case op.Kind == OpConflict:
+			fmt.Fprintf(w, "[DRY-RUN] Conflict %s: %s\n", op.Path, op.Reason)
+		case !changed:
+		case c.Dir:
+			fmt.Fprintf(w, "[DRY-RUN] Create %s/\n", op.Path)
+		case c.Link != "":
+			fmt.Fprintf(w, "[DRY-RUN] Link %s -> %s\n", op.Path, c.Link)
+		case c.Existed:
+			fmt.Fprintf(w, "[DRY-RUN] Overwrite %s\n", op.Path)
+		default:
+			fmt.Fprintf(w, "[DRY-RUN] Create %s\n", op.Path)
+		}
+	}
+	fmt.Fprintln(w, p.Summary())
+	return nil
+}
@@ -0,0 +1,115 @@
+package fs
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+// DriftKind classifies a difference between a spec and a directory.
+type DriftKind string
+
+const (
+	Missing      DriftKind = "missing"       // in the spec, not on disk
+	Extra        DriftKind = "extra"         // on disk, not in the spec
+	KindMismatch DriftKind = "kind-mismatch" // e.g. file in one, directory in the other
+)
+
+// Drift is a single difference found by Compare.
+type Drift struct {
+	Path     string
+	Kind     DriftKind
+	Expected parser.NodeKind `json:",omitempty"` // kind declared in the spec
+	Actual   parser.NodeKind `json:",omitempty"` // kind found on disk
+	Line     int             `json:",omitempty"` // spec line, when known
+}
+
+type CompareOptions struct {
+	IgnoreExtra bool        // only report missing and mismatched paths
+	Scan        ScanOptions // how to walk the directory when looking for extras
+}
+
+// Report lists every difference between a spec and a directory.
+type Report struct {
+	Root  string
+	Drift []Drift
+}
+
+// Clean reports whether the directory matches the spec.
+func (r Report) Clean() bool {
+	return len(r.Drift) == 0
+}
+
+// Compare checks rootDir against the spec nodes using the same path joining
+// as Apply. Directories implied by a nested path (e.g. "src" for
+// "src/main.go") count as part of the spec. If rootDir does not exist,
+// every node is missing.
+func Compare(rootDir string, nodes []parser.Node, opts CompareOptions) (Report, error) {
+	report := Report{Root: rootDir, Drift: make([]Drift, 0)}
+	inSpec := make(map[string]bool, len(nodes))
+
+	for _, node := range nodes {
+		inSpec[node.Path] = true
+		for dir := path.Dir(node.Path); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			inSpec[dir] = true
+		}
+
+		info, err := os.Lstat(targetPath(rootDir, node.Path))
+		if os.IsNotExist(err) {
+			report.Drift = append(report.Drift, Drift{Path: node.Path, Kind: Missing, Expected: node.Kind, Line: node.Line})
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		actual := parser.File
+		switch {
+		case info.IsDir():
+			actual = parser.Dir
+		case info.Mode()&os.ModeSymlink != 0:
+			actual = parser.Symlink
+		}
+		if actual != node.Kind {
+			report.Drift = append(report.Drift, Drift{Path: node.Path, Kind: KindMismatch, Expected: node.Kind, Actual: actual, Line: node.Line})
+		}
+	}
+
+	// A root that does not exist holds nothing extra: everything is missing.
+	if _, err := os.Stat(rootDir); opts.IgnoreExtra || os.IsNotExist(err) {
+		return report, nil
+	}
+
+	onDisk, err := Scan(rootDir, opts.Scan)
+	if err != nil {
+		return report, err
+	}
+	extraDirs := make(map[string]bool)
+	for _, n := range onDisk {
+		if inSpec[n.Path] || underAny(n.Path, extraDirs) {
+			continue
+		}
+		// Only report the topmost extra directory, not everything inside it.
+		if n.Kind == parser.Dir {
+			extraDirs[n.Path] = true
+		}
+		report.Drift = append(report.Drift, Drift{Path: n.Path, Kind: Extra, Actual: n.Kind})
+	}
+	return report, nil
+}
+
+func underAny(p string, dirs map[string]bool) bool {
+	for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if dirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// targetPath maps a node path onto the output directory.
+func targetPath(rootDir, nodePath string) string {
+	return filepath.Join(rootDir, filepath.FromSlash(nodePath))
+}
@@ -0,0 +1,70 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+func TestCompare(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "src", "util"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "tmp", "cache"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "src", "main.go"), nil, 0644)
+	os.WriteFile(filepath.Join(tmpDir, "docs"), nil, 0644)
+	os.WriteFile(filepath.Join(tmpDir, "tmp", "cache", "x"), nil, 0644)
+
+	nodes := []parser.Node{
+		{Path: "src/main.go", Kind: parser.File},
+		{Path: "src/util", Kind: parser.Dir},
+		{Path: "src/api", Kind: parser.Dir},
+		{Path: "docs", Kind: parser.Dir},
+	}
+
+	report, err := Compare(tmpDir, nodes, CompareOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]DriftKind)
+	for _, d := range report.Drift {
+		got[d.Path] = d.Kind
+	}
+	expected := map[string]DriftKind{
+		"src/api": Missing,
+		"docs":    KindMismatch,
+		"tmp":     Extra,
+	}
+	if len(got) != len(expected) {
+		t.Errorf("expected %d differences, got %v", len(expected), report.Drift)
+	}
+	for p, kind := range expected {
+		if got[p] != kind {
+			t.Errorf("%s: expected %s, got %q", p, kind, got[p])
+		}
+	}
+
+	report, err = Compare(tmpDir, nodes[:2], CompareOptions{IgnoreExtra: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Clean() {
+		t.Errorf("expected a clean report, got %v", report.Drift)
+	}
+}
+
+func TestCompare_MissingRoot(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "src", Kind: parser.Dir},
+		{Path: "src/main.go", Kind: parser.File},
+	}
+	report, err := Compare(filepath.Join(t.TempDir(), "out"), nodes, CompareOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Drift) != 2 || report.Drift[0].Kind != Missing || report.Drift[1].Kind != Missing {
+		t.Errorf("expected everything missing, got %v", report.Drift)
+	}
+}
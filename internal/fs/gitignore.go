@@ -0,0 +1,137 @@
+package fs
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is a single line of a .gitignore file (or an --exclude glob).
+type ignoreRule struct {
+	base     string // slash path of the directory the rule applies to ("" = scan root)
+	pattern  string
+	negate   bool // "!pattern" re-includes a path
+	dirOnly  bool // "pattern/" only matches directories
+	anchored bool // pattern contains a slash, so it is matched against the whole path
+}
+
+// ignoreMatcher evaluates gitignore-style rules; the last matching rule wins.
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+func newIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	r := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, "\\") // "\#file" and "\!file" escape the first char
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	r.pattern = line
+	return r, true
+}
+
+// add registers extra patterns (e.g. --exclude globs) relative to the scan root.
+func (m *ignoreMatcher) add(patterns ...string) {
+	for _, p := range patterns {
+		if r, ok := newIgnoreRule("", p); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+}
+
+// load reads the .gitignore in dir (absolute) whose scan-relative path is rel.
+func (m *ignoreMatcher) load(dir, rel string) error {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if r, ok := newIgnoreRule(rel, sc.Text()); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+	return sc.Err()
+}
+
+// ignored reports whether the scan-relative slash path rel should be skipped.
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(rel, r.base+"/")
+		}
+
+		var ok bool
+		if r.anchored {
+			ok = matchGlob(r.pattern, sub)
+		} else {
+			ok = matchGlob(r.pattern, path.Base(sub))
+		}
+		if ok {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// matchGlob matches a slash-separated path against a pattern where "**"
+// stands for any number of path segments and other segments use path.Match.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			if len(pat) == 1 {
+				return true
+			}
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pat[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
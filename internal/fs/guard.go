@@ -0,0 +1,134 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+// checkRel rejects absolute paths and paths that climb out with "..".
+func checkRel(rel string) error {
+	clean := path.Clean(rel)
+	if path.IsAbs(clean) || filepath.IsAbs(filepath.FromSlash(clean)) {
+		return fmt.Errorf("%q is an absolute path", rel)
+	}
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("%q climbs out of the output directory", rel)
+	}
+	return nil
+}
+
+// checkPath makes sure the slash path rel, joined onto rootDir, stays inside
+// rootDir: no ".." escapes, no absolute paths, and no existing symlink along
+// the way that points outside the root.
+func checkPath(rootDir, rel string) error {
+	if err := checkRel(rel); err != nil {
+		return err
+	}
+	clean := path.Clean(rel)
+
+	realRoot, err := filepath.EvalSymlinks(rootDir)
+	if os.IsNotExist(err) {
+		return nil // nothing exists yet, so nothing can redirect us
+	}
+	if err != nil {
+		return err
+	}
+	if realRoot, err = filepath.Abs(realRoot); err != nil {
+		return err
+	}
+
+	// Walk the parts of the path that already exist, resolving symlinks.
+	cur := rootDir
+	for _, part := range strings.Split(clean, "/") {
+		if part == "." {
+			continue
+		}
+		cur = filepath.Join(cur, part)
+		info, err := os.Lstat(cur)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			if !info.IsDir() {
+				return nil // a file in the way is a conflict for the planner, not an escape
+			}
+			continue
+		}
+
+		resolved, err := filepath.EvalSymlinks(cur)
+		if os.IsNotExist(err) {
+			// Dangling link: writing through it would create its target.
+			target, _ := os.Readlink(cur)
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(cur), target)
+			}
+			resolved = target
+		} else if err != nil {
+			return err
+		}
+		if resolved, err = filepath.Abs(resolved); err != nil {
+			return err
+		}
+		if !within(realRoot, resolved) {
+			return fmt.Errorf("%q goes through symlink %s, which points outside the output directory (%s)", rel, cur, resolved)
+		}
+	}
+	return nil
+}
+
+// within reports whether p is root or lies below it.
+func within(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkLink vets the target of the symlink at link: it must be relative and
+// stay inside the root once resolved from the link's directory.
+func checkLink(link, target string, check func(rel string) error) error {
+	resolved := path.Join(path.Dir(link), target)
+	switch {
+	case target == "":
+		return fmt.Errorf("symlink %q has no target", link)
+	case path.IsAbs(target) || filepath.IsAbs(target):
+		return fmt.Errorf("symlink %q has an absolute target %q", link, target)
+	case checkRel(resolved) != nil:
+		return fmt.Errorf("symlink %q points outside the output directory (-> %s)", link, target)
+	}
+	if err := check(resolved); err != nil {
+		return fmt.Errorf("symlink %q: %w", link, err)
+	}
+	return nil
+}
+
+// CheckPaths reports every node that would be written outside rootDir,
+// through ".." components or through symlinks that already exist on disk,
+// and every symlink in the spec whose target points outside it.
+func CheckPaths(rootDir string, nodes []parser.Node) []parser.Diagnostic {
+	var diags []parser.Diagnostic
+	for _, n := range nodes {
+		err := checkPath(rootDir, n.Path)
+		if err == nil && n.Kind == parser.Symlink {
+			err = checkLink(n.Path, n.Target, func(rel string) error { return checkPath(rootDir, rel) })
+		}
+		if err != nil {
+			diags = append(diags, parser.Diagnostic{
+				Severity: parser.SeverityError,
+				Code:     parser.CodeOutsideRoot,
+				Line:     n.Line,
+				Message:  err.Error(),
+			})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,57 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+func TestCheckPaths(t *testing.T) {
+	outside := t.TempDir()
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "real"), 0755)
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	os.Symlink(filepath.Join(root, "real"), filepath.Join(root, "inside"))
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"src/main.go", true},
+		{"a/../b.txt", true},
+		{"../../.ssh/authorized_keys", false},
+		{"escape/cron.d/x", false},
+		{"escape", false},
+		{"inside/file.txt", true},
+	}
+	for _, tt := range tests {
+		err := checkPath(root, tt.path)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkPath(%q): ok=%v, got err %v", tt.path, tt.ok, err)
+		}
+	}
+
+	nodes := []parser.Node{
+		{Path: "escape/x", Kind: parser.File, Line: 3},
+		{Path: "fine.txt", Kind: parser.File},
+	}
+	diags := CheckPaths(root, nodes)
+	if len(diags) != 1 || diags[0].Line != 3 || diags[0].Code != parser.CodeOutsideRoot {
+		t.Errorf("expected one outside-root diagnostic on line 3, got %v", diags)
+	}
+
+	// Apply refuses the whole build, and writes nothing outside.
+	if err := Apply(root, nodes, ApplyOptions{}); err == nil {
+		t.Error("Apply should refuse paths that escape the root")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "x")); !os.IsNotExist(err) {
+		t.Error("Apply wrote through a symlink outside the root")
+	}
+	if _, err := os.Stat(filepath.Join(root, "fine.txt")); !os.IsNotExist(err) {
+		t.Error("refused build still wrote other files")
+	}
+}
@@ -0,0 +1,295 @@
+package fs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StateDir is the directory tr2rl keeps its bookkeeping in, inside the build root.
+const StateDir = ".tr2rl"
+
+// Journal records what a single build changed, so it can be undone later.
+type Journal struct {
+	ID      string
+	Time    time.Time
+	Entries []JournalEntry
+
+	// AllowOutside is set for builds made with --allow-outside, whose
+	// entries may lie outside the build root.
+	AllowOutside bool `json:",omitempty"`
+}
+
+// JournalEntry is one change made by a build.
+type JournalEntry struct {
+	Action string // "mkdir", "create", "overwrite" or "symlink"
+	Path   string // slash path relative to the build root ("." for the root itself)
+	SHA256 string `json:",omitempty"` // hash of the contents the build wrote (of the target, for a symlink)
+	Backup string `json:",omitempty"` // previous contents, relative to the build root
+	Mode   uint32 `json:",omitempty"` // previous permissions of an overwritten file
+}
+
+type UndoOptions struct {
+	Log   io.Writer // progress lines; nil discards them
+	Force bool      // delete/restore files even if they changed since the build
+
+	// AllowOutside permits undoing a build made with --allow-outside.
+	// Any other journal must stay inside the root.
+	AllowOutside bool
+}
+
+func journalDir(root string) string {
+	return filepath.Join(root, StateDir, "journal")
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return hashBytes(data), nil
+}
+
+// writeJournal saves the changes recorded by t under .tr2rl in the root of
+// fsys, backing up the previous contents of overwritten files. It returns
+// the journal ID.
+func writeJournal(fsys FS, t *txn, allowOutside bool) (string, error) {
+	now := time.Now().UTC()
+	j := Journal{ID: now.Format("20060102-150405.000000"), Time: now, AllowOutside: allowOutside}
+	backupRoot := path.Join(StateDir, "backup", j.ID)
+	state := &txn{dst: fsys} // its own changes are not part of the build
+
+	for _, c := range t.changes {
+		e := JournalEntry{Action: c.action, Path: c.path}
+
+		if c.action != actionMkdir {
+			e.SHA256 = hashBytes(c.data)
+		}
+		if c.action == actionOverwrite {
+			e.Backup = path.Join(backupRoot, c.path)
+			e.Mode = uint32(c.mode)
+			if err := state.mkdirAll(path.Dir(e.Backup), 0); err != nil {
+				return "", err
+			}
+			if err := fsys.WriteFile(e.Backup, c.old, 0600); err != nil {
+				return "", err
+			}
+		}
+		j.Entries = append(j.Entries, e)
+	}
+
+	dir := path.Join(StateDir, "journal")
+	if err := state.mkdirAll(dir, 0); err != nil {
+		return "", err
+	}
+	// Keep the bookkeeping out of version control.
+	ignore := path.Join(StateDir, ".gitignore")
+	if _, err := fsys.Stat(ignore); errors.Is(err, iofs.ErrNotExist) {
+		fsys.WriteFile(ignore, []byte("*\n"), 0644)
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return j.ID, fsys.WriteFile(path.Join(dir, j.ID+".json"), data, 0644)
+}
+
+// ListJournals returns the IDs of all builds recorded under root, oldest first.
+func ListJournals(root string) ([]string, error) {
+	entries, err := os.ReadDir(journalDir(root))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// LoadJournal reads the journal with the given ID (or unique ID prefix).
+// An empty id selects the most recent build.
+func LoadJournal(root, id string) (*Journal, error) {
+	ids, err := ListJournals(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no build journal found in %s", filepath.Join(root, StateDir))
+	}
+
+	match := ""
+	if id == "" {
+		match = ids[len(ids)-1]
+	} else {
+		for _, candidate := range ids {
+			if strings.HasPrefix(candidate, id) {
+				if match != "" {
+					return nil, fmt.Errorf("journal id %q is ambiguous", id)
+				}
+				match = candidate
+			}
+		}
+		if match == "" {
+			return nil, fmt.Errorf("no build journal with id %q", id)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(journalDir(root), match+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("invalid journal %s: %w", match, err)
+	}
+	j.ID = match // the file name, not the contents, says which backups are its own
+	return &j, nil
+}
+
+// checkJournal makes sure every path in j stays inside root. Journals are
+// plain JSON in the build root, so one that came with a cloned repository
+// must not reach anywhere else. Only the entries of a build made with
+// --allow-outside may leave the root, and only if the undo allows it too.
+func checkJournal(root string, j *Journal, allowOutside bool) error {
+	if j.AllowOutside && !allowOutside {
+		return fmt.Errorf("build %s was made with --allow-outside; undo it with --allow-outside", j.ID)
+	}
+	backups := path.Join(StateDir, "backup", j.ID) + "/"
+	for _, e := range j.Entries {
+		if !j.AllowOutside {
+			if err := checkPath(root, e.Path); err != nil {
+				return fmt.Errorf("invalid journal %s: %w", j.ID, err)
+			}
+		}
+		if e.Backup == "" {
+			continue
+		}
+		if !strings.HasPrefix(path.Clean(e.Backup), backups) {
+			return fmt.Errorf("invalid journal %s: backup %q is not under %s", j.ID, e.Backup, backups)
+		}
+		if err := checkPath(root, e.Backup); err != nil {
+			return fmt.Errorf("invalid journal %s: %w", j.ID, err)
+		}
+	}
+	return nil
+}
+
+// Undo reverses a recorded build: created files and directories are removed
+// and overwritten files are restored from their backups. Unless opts.Force is
+// set, it refuses to do anything if a file changed since the build.
+// Directories that are no longer empty are kept.
+func Undo(root string, j *Journal, opts UndoOptions) error {
+	w := opts.Log
+	if w == nil {
+		w = io.Discard
+	}
+	if err := checkJournal(root, j, opts.AllowOutside); err != nil {
+		return err
+	}
+
+	if !opts.Force {
+		var changed []string
+		for _, e := range j.Entries {
+			if e.Action == actionMkdir {
+				continue
+			}
+			var sum string
+			var err error
+			if e.Action == actionSymlink {
+				var target string
+				target, err = os.Readlink(targetPath(root, e.Path))
+				sum = hashBytes([]byte(target))
+			} else {
+				sum, err = hashFile(targetPath(root, e.Path))
+			}
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if sum != e.SHA256 {
+				changed = append(changed, e.Path)
+			}
+		}
+		if len(changed) > 0 {
+			return fmt.Errorf("refusing to undo: %d file(s) changed since the build (%s); use --force to undo anyway",
+				len(changed), strings.Join(changed, ", "))
+		}
+	}
+
+	var errs []error
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		e := j.Entries[i]
+		full := targetPath(root, e.Path)
+
+		switch e.Action {
+		case actionOverwrite:
+			old, err := os.ReadFile(targetPath(root, e.Backup))
+			if err == nil {
+				err = restoreFile(DirFS(root), e.Path, old, os.FileMode(e.Mode))
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore %s: %w", e.Path, err))
+				continue
+			}
+			fmt.Fprintf(w, "[OK] Restored %s\n", e.Path)
+
+		case actionCreate, actionSymlink:
+			if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to remove %s: %w", e.Path, err))
+				continue
+			}
+			fmt.Fprintf(w, "[OK] Removed %s\n", e.Path)
+
+		case actionMkdir:
+			if e.Path == "." {
+				continue // the root goes last, once our own bookkeeping is gone
+			}
+			if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(w, "[KEEP] %s/ is not empty\n", e.Path)
+				continue
+			}
+			fmt.Fprintf(w, "[OK] Removed %s/\n", e.Path)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	// The build is gone: drop its journal and backups, then any empty state dirs.
+	os.Remove(filepath.Join(journalDir(root), j.ID+".json"))
+	os.RemoveAll(filepath.Join(root, StateDir, "backup", j.ID))
+	if ids, _ := ListJournals(root); len(ids) == 0 {
+		os.RemoveAll(filepath.Join(root, StateDir))
+	}
+	if len(j.Entries) > 0 && j.Entries[0].Action == actionMkdir && j.Entries[0].Path == "." {
+		if os.Remove(root) == nil {
+			fmt.Fprintf(w, "[OK] Removed %s\n", root)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+func TestJournal_Undo(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "out")
+	os.MkdirAll(root, 0755)
+	os.WriteFile(filepath.Join(root, "keep.txt"), []byte("original"), 0644)
+
+	nodes := []parser.Node{
+		{Path: "keep.txt", Kind: parser.File, Content: "replaced", Mode: 0600},
+		{Path: "src/main.go", Kind: parser.File, Content: "package main\n"},
+		{Path: "docs", Kind: parser.Dir},
+		{Path: "docs/main.go", Kind: parser.Symlink, Target: "../src/main.go"},
+	}
+	if err := Apply(root, nodes, ApplyOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := ListJournals(root)
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("expected one journal, got %v (err %v)", ids, err)
+	}
+
+	// A file edited after the build blocks the undo.
+	mainPath := filepath.Join(root, "src", "main.go")
+	os.WriteFile(mainPath, []byte("package main // edited\n"), 0644)
+
+	j, err := LoadJournal(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Undo(root, j, UndoOptions{}); err == nil {
+		t.Fatal("undo should refuse to delete a file that changed since the build")
+	}
+	if _, err := os.Stat(mainPath); err != nil {
+		t.Fatal("refused undo still deleted files")
+	}
+
+	os.WriteFile(mainPath, []byte("package main\n"), 0644)
+	if err := Undo(root, j, UndoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{"src", "docs", StateDir} {
+		if _, err := os.Stat(filepath.Join(root, p)); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed by undo", p)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "keep.txt"))
+	if string(data) != "original" {
+		t.Errorf("undo did not restore the overwritten file, got %q", data)
+	}
+	if info, _ := os.Stat(filepath.Join(root, "keep.txt")); info.Mode().Perm() == 0600 {
+		t.Error("undo did not restore the permissions of the overwritten file")
+	}
+}
+
+func TestUndo_RejectsPathsOutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "repo")
+	victim := filepath.Join(dir, "victim.txt")
+	os.WriteFile(victim, []byte("keep me"), 0644)
+
+	// A journal that came with a cloned repository.
+	journals := []string{
+		`{"ID":"1","Entries":[{"Action":"create","Path":"../victim.txt"}]}`,
+		`{"ID":"2","Entries":[{"Action":"overwrite","Path":"a.txt","Backup":"../victim.txt"}]}`,
+		`{"ID":"3","Entries":[{"Action":"create","Path":"../victim.txt"}],"AllowOutside":true}`,
+		`{"ID":"../..","Entries":[]}`,
+	}
+	os.MkdirAll(journalDir(root), 0755)
+	for i, data := range journals {
+		name := filepath.Join(journalDir(root), string(rune('1'+i))+".json")
+		os.WriteFile(name, []byte(data), 0644)
+	}
+
+	for i := range journals[:3] {
+		j, err := LoadJournal(root, string(rune('1'+i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Undo(root, j, UndoOptions{Force: true}); err == nil {
+			t.Errorf("journal %s: undo should refuse paths outside the root", j.ID)
+		}
+		if _, err := os.Stat(victim); err != nil {
+			t.Fatalf("journal %s: undo touched a file outside the root", j.ID)
+		}
+	}
+
+	// The ID comes from the file name, so cleanup stays in the journal dir.
+	j, err := LoadJournal(root, "4")
+	if err != nil || j.ID != "4" {
+		t.Fatalf("LoadJournal = %+v, %v", j, err)
+	}
+	if err := Undo(root, j, UndoOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(journalDir(root)); err != nil {
+		t.Fatal("undo removed the root along with a crafted journal ID")
+	}
+}
@@ -0,0 +1,212 @@
+package fs
+
+import (
+	"errors"
+	iofs "io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// MemFS is an FS held in memory. Made with NewOverlay it sits on top of a
+// read-only fs.FS: lookups fall through to the base, writes stay in memory,
+// and Changes reports what differs from the base. Like an object store it
+// does not insist that parent directories exist.
+type MemFS struct {
+	base    iofs.FS // nil for a plain MemFS
+	entries map[string]*memEntry
+	order   []string // names in the order they were first written
+}
+
+type memEntry struct {
+	dir     bool
+	data    []byte
+	link    string // target of a symlink
+	mode    os.FileMode
+	modTime time.Time
+	existed bool // the base had this name before it was written
+}
+
+// NewMemFS returns an empty in-memory FS.
+func NewMemFS() *MemFS {
+	return NewOverlay(nil)
+}
+
+// NewOverlay returns an in-memory FS layered over base, which is never
+// written to. Use os.DirFS(dir) to preview a build into dir.
+func NewOverlay(base iofs.FS) *MemFS {
+	return &MemFS{base: base, entries: make(map[string]*memEntry)}
+}
+
+// maxLinkHops bounds how many symlinks ReadFile follows, so a loop fails.
+const maxLinkHops = 40
+
+// Stat describes name, looking in memory first and then in the base. A
+// symlink in memory is described itself, not followed.
+func (m *MemFS) Stat(name string) (iofs.FileInfo, error) {
+	name = path.Clean(name)
+	if name == "." {
+		return memInfo{name: ".", e: &memEntry{dir: true, mode: DefaultDirMode}}, nil
+	}
+	if e, ok := m.entries[name]; ok {
+		return memInfo{name: path.Base(name), e: e}, nil
+	}
+	if m.base != nil && iofs.ValidPath(name) {
+		return iofs.Stat(m.base, name)
+	}
+	return nil, &iofs.PathError{Op: "stat", Path: name, Err: iofs.ErrNotExist}
+}
+
+// Mkdir records a directory. It fails if name already exists.
+func (m *MemFS) Mkdir(name string, perm os.FileMode) error {
+	name = path.Clean(name)
+	existed, err := m.exists(name)
+	if err != nil || existed {
+		return &iofs.PathError{Op: "mkdir", Path: name, Err: iofs.ErrExist}
+	}
+	m.put(name, &memEntry{dir: true, mode: perm.Perm()})
+	return nil
+}
+
+// WriteFile creates or replaces the file name. It fails if name is a directory.
+func (m *MemFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	name = path.Clean(name)
+	existed, err := m.exists(name)
+	if err != nil {
+		return &iofs.PathError{Op: "write", Path: name, Err: err}
+	}
+	m.put(name, &memEntry{data: append([]byte(nil), data...), mode: perm.Perm(), existed: existed})
+	return nil
+}
+
+// Symlink records name as a symbolic link to target. It fails if name
+// already exists.
+func (m *MemFS) Symlink(target, name string) error {
+	name = path.Clean(name)
+	if _, err := m.Stat(name); err == nil {
+		return &iofs.PathError{Op: "symlink", Path: name, Err: iofs.ErrExist}
+	}
+	m.put(name, &memEntry{link: target, mode: iofs.ModePerm})
+	return nil
+}
+
+// Remove deletes name from memory. A directory must be empty; names that
+// only exist in the base cannot be removed.
+func (m *MemFS) Remove(name string) error {
+	name = path.Clean(name)
+	e, ok := m.entries[name]
+	if !ok {
+		return &iofs.PathError{Op: "remove", Path: name, Err: iofs.ErrNotExist}
+	}
+	if e.dir {
+		for other := range m.entries {
+			if strings.HasPrefix(other, name+"/") {
+				return &iofs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+			}
+		}
+	}
+	delete(m.entries, name)
+	for i, n := range m.order {
+		if n == name {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Readlink returns the target of a symlink in memory.
+func (m *MemFS) Readlink(name string) (string, error) {
+	name = path.Clean(name)
+	if e, ok := m.entries[name]; ok && e.link != "" {
+		return e.link, nil
+	}
+	return "", &iofs.PathError{Op: "readlink", Path: name, Err: iofs.ErrInvalid}
+}
+
+// ReadFile returns the contents of a file in memory or in the base,
+// following symlinks made in memory.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	name = path.Clean(name)
+	for hops := 0; ; hops++ {
+		e, ok := m.entries[name]
+		switch {
+		case !ok:
+			if m.base != nil && iofs.ValidPath(name) {
+				return iofs.ReadFile(m.base, name)
+			}
+			return nil, &iofs.PathError{Op: "read", Path: name, Err: iofs.ErrNotExist}
+		case e.dir:
+			return nil, &iofs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+		case e.link == "":
+			return append([]byte(nil), e.data...), nil
+		case hops == maxLinkHops:
+			return nil, &iofs.PathError{Op: "read", Path: name, Err: errors.New("too many levels of symbolic links")}
+		}
+		name = path.Join(path.Dir(name), e.link)
+	}
+}
+
+// exists reports whether name is a file; a directory is an error.
+func (m *MemFS) exists(name string) (bool, error) {
+	info, err := m.Stat(name)
+	switch {
+	case errors.Is(err, iofs.ErrNotExist), err != nil && !iofs.ValidPath(name):
+		return false, nil
+	case err != nil:
+		return false, err
+	case info.IsDir():
+		return true, errors.New("is a directory")
+	}
+	return true, nil
+}
+
+func (m *MemFS) put(name string, e *memEntry) {
+	if old, ok := m.entries[name]; ok {
+		e.existed = old.existed
+	} else {
+		m.order = append(m.order, name)
+	}
+	e.modTime = time.Now()
+	m.entries[name] = e
+}
+
+// Change is one difference between a MemFS and its base.
+type Change struct {
+	Path    string
+	Dir     bool
+	Existed bool   // a file that was replaced rather than created
+	Link    string // target, if the change is a symlink
+}
+
+// Changes lists everything written, in the order it was first written.
+func (m *MemFS) Changes() []Change {
+	changes := make([]Change, 0, len(m.order))
+	for _, name := range m.order {
+		e := m.entries[name]
+		changes = append(changes, Change{Path: name, Dir: e.dir, Existed: e.existed, Link: e.link})
+	}
+	return changes
+}
+
+// memInfo is the fs.FileInfo of an entry in memory.
+type memInfo struct {
+	name string
+	e    *memEntry
+}
+
+func (i memInfo) Name() string { return i.name }
+func (i memInfo) Size() int64  { return int64(len(i.e.data)) }
+func (i memInfo) Mode() os.FileMode {
+	switch {
+	case i.e.dir:
+		return iofs.ModeDir | i.e.mode
+	case i.e.link != "":
+		return iofs.ModeSymlink | i.e.mode
+	}
+	return i.e.mode
+}
+func (i memInfo) ModTime() time.Time { return i.e.modTime }
+func (i memInfo) IsDir() bool        { return i.e.dir }
+func (i memInfo) Sys() any           { return nil }
@@ -0,0 +1,171 @@
+package fs
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+func TestApplyTo_MemFS(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "src/main.go", Kind: parser.File, Content: "package main\n"},
+		{Path: "docs", Kind: parser.Dir},
+	}
+	mem := NewMemFS()
+	if err := ApplyTo(mem, nodes, ApplyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := mem.ReadFile("src/main.go")
+	if err != nil || string(data) != "package main\n" {
+		t.Errorf("src/main.go = %q, %v", data, err)
+	}
+	if info, err := mem.Stat("docs"); err != nil || !info.IsDir() {
+		t.Errorf("docs: %v, %v", info, err)
+	}
+
+	// Symlinks are kept as links and read through.
+	link := []parser.Node{{Path: "docs/main.go", Kind: parser.Symlink, Target: "../src/main.go"}}
+	if err := ApplyTo(mem, link, ApplyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := mem.Stat("docs/main.go"); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("docs/main.go: %v, %v", info, err)
+	}
+	if data, _ := mem.ReadFile("docs/main.go"); string(data) != "package main\n" {
+		t.Errorf("reading through docs/main.go got %q", data)
+	}
+
+	// A second build is planned against what is there: nothing to do.
+	mem.WriteFile("src/main.go", []byte("edited\n"), 0644)
+	plan, err := NewPlanFS(mem, nodes, ApplyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Count(OpSkipExists) != 1 || plan.Count(OpMkdir) != 0 || plan.Count(OpCreate) != 0 {
+		t.Errorf("unexpected plan: %s", plan.Summary())
+	}
+	if err := ApplyTo(mem, nodes, ApplyOptions{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := mem.ReadFile("src/main.go"); string(data) != "package main\n" {
+		t.Errorf("--force did not overwrite: %q", data)
+	}
+
+	// A file in the way of a directory is a conflict.
+	mem.WriteFile("lib", nil, 0644)
+	err = ApplyTo(mem, []parser.Node{{Path: "lib/a.go", Kind: parser.File}}, ApplyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "conflict") {
+		t.Errorf("err = %v, want a conflict", err)
+	}
+}
+
+func TestExecuteTo_MemFSRollbackAndJournal(t *testing.T) {
+	mem := NewMemFS()
+	mem.WriteFile("keep.txt", []byte("original"), 0600)
+	mem.WriteFile("late.txt", []byte("appeared after planning"), 0644)
+
+	plan := &Plan{Version: PlanVersion, Ops: []Op{
+		{Kind: OpMkdir, Path: "new/deep"},
+		{Kind: OpCreate, Path: "new/deep/a.txt", Content: "a"},
+		{Kind: OpOverwrite, Path: "keep.txt", Content: "replaced"},
+		{Kind: OpCreate, Path: "late.txt", Content: "boom"}, // fails: file exists
+	}}
+	if err := plan.ExecuteTo(mem, ExecuteOptions{}); err == nil {
+		t.Fatal("expected the stale create to fail")
+	}
+	if _, err := mem.Stat("new"); err == nil {
+		t.Error("rollback left the created directory behind")
+	}
+	if data, _ := mem.ReadFile("keep.txt"); string(data) != "original" {
+		t.Errorf("rollback did not restore keep.txt, got %q", data)
+	}
+	if info, _ := mem.Stat("keep.txt"); info.Mode().Perm() != 0600 {
+		t.Errorf("rollback did not restore the mode of keep.txt, got %v", info.Mode().Perm())
+	}
+
+	// A build that goes through is journaled in the FS, like on disk.
+	plan.Ops = plan.Ops[:3]
+	if err := plan.ExecuteTo(mem, ExecuteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := mem.Stat(StateDir + "/journal"); err != nil || !info.IsDir() {
+		t.Errorf("no journal in the MemFS: %v", err)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	base := fstest.MapFS{
+		"README.md":   {Data: []byte("base\n")},
+		"src/util.go": {Data: []byte("package src\n")},
+	}
+	ov := NewOverlay(base)
+
+	if err := ov.Mkdir("src", 0755); err == nil {
+		t.Error("Mkdir of a directory in the base should fail")
+	}
+	if err := ov.WriteFile("src", nil, 0644); err == nil {
+		t.Error("WriteFile over a directory in the base should fail")
+	}
+	ov.WriteFile("README.md", []byte("new\n"), 0644)
+	ov.Mkdir("docs", 0755)
+	ov.WriteFile("docs/a.md", nil, 0644)
+
+	if data, _ := ov.ReadFile("src/util.go"); string(data) != "package src\n" {
+		t.Errorf("base file not visible: %q", data)
+	}
+	if data, _ := base.ReadFile("README.md"); string(data) != "base\n" {
+		t.Errorf("base was written to: %q", data)
+	}
+	want := []Change{{Path: "README.md", Existed: true}, {Path: "docs", Dir: true}, {Path: "docs/a.md"}}
+	got := ov.Changes()
+	if len(got) != len(want) {
+		t.Fatalf("Changes() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(dir+"/keep.txt", []byte("x"), 0644)
+	os.WriteFile(dir+"/over.txt", []byte("x"), 0644)
+
+	nodes := []parser.Node{
+		{Path: "keep.txt", Kind: parser.File},
+		{Path: "src/main.go", Kind: parser.File},
+		{Path: "over.txt/x", Kind: parser.File},
+	}
+	plan, err := NewPlan(dir, nodes, ApplyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := plan.Preview(&b, os.DirFS(dir)); err != nil {
+		t.Fatal(err)
+	}
+	want := `[DRY-RUN] Skip keep.txt: file exists (use --force to overwrite)
+[DRY-RUN] Create src/
+[DRY-RUN] Create src/main.go
+[DRY-RUN] Conflict over.txt: a file exists where a directory is needed
+Plan: 1 dir(s) to create, 1 file(s) to create, 0 to overwrite, 1 skipped, 1 conflict(s).
+`
+	if b.String() != want {
+		t.Errorf("Preview:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	plan, _ = NewPlan(dir, []parser.Node{{Path: "over.txt", Kind: parser.File}}, ApplyOptions{Force: true})
+	b.Reset()
+	plan.Preview(&b, os.DirFS(dir))
+	if !strings.HasPrefix(b.String(), "[DRY-RUN] Overwrite over.txt\n") {
+		t.Errorf("Preview with --force:\n%s", b.String())
+	}
+	if data, _ := os.ReadFile(dir + "/over.txt"); string(data) != "x" {
+		t.Error("Preview wrote to disk")
+	}
+}
@@ -0,0 +1,266 @@
+package fs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cytificlabs/tr2rl/internal/content"
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+// PlanVersion is bumped whenever the serialised Plan format changes incompatibly.
+const PlanVersion = 1
+
+// OpKind is the action a plan takes for one path.
+type OpKind string
+
+const (
+	OpMkdir      OpKind = "mkdir"       // create a directory
+	OpCreate     OpKind = "create"      // create a new file
+	OpOverwrite  OpKind = "overwrite"   // replace an existing file (--force)
+	OpSymlink    OpKind = "symlink"     // create a symlink to Op.Target
+	OpSkipExists OpKind = "skip-exists" // file exists and --force is off
+	OpConflict   OpKind = "conflict"    // a file is in the way of a directory (or vice versa), or the path escapes the root
+)
+
+// Op is a single planned filesystem operation.
+type Op struct {
+	Kind    OpKind
+	Path    string      // slash path relative to Plan.Root
+	Content string      `json:",omitempty"` // file contents for create/overwrite
+	Mode    os.FileMode `json:",omitempty"` // permission bits from the spec; 0 means the default
+	Target  string      `json:",omitempty"` // symlink target, relative to the link's directory
+	Reason  string      `json:",omitempty"` // why a path is skipped or conflicting
+	Line    int         `json:",omitempty"` // spec line the op came from
+}
+
+// Plan is the full list of operations needed to materialise a spec,
+// computed from the current state of the filesystem. It can be saved,
+// reviewed, and executed later.
+type Plan struct {
+	Version int
+	Root    string
+	Ops     []Op
+}
+
+// NewPlan decides what Apply would do for every node without touching disk.
+// Missing parent directories get their own mkdir ops.
+func NewPlan(rootDir string, nodes []parser.Node, opts ApplyOptions) (*Plan, error) {
+	stat := func(rel string) (os.FileInfo, error) { return os.Stat(targetPath(rootDir, rel)) }
+	lstat := func(rel string) (os.FileInfo, error) { return os.Lstat(targetPath(rootDir, rel)) }
+	check := func(rel string) error { return checkPath(rootDir, rel) }
+	return newPlan(rootDir, nodes, opts, stat, lstat, check)
+}
+
+// NewEmptyPlan plans a build into an empty directory without looking at
+// disk: every directory is a mkdir and every file a create. Export uses it
+// for scripts that run on another machine.
+func NewEmptyPlan(nodes []parser.Node, opts ApplyOptions) *Plan {
+	missing := func(string) (os.FileInfo, error) { return nil, os.ErrNotExist }
+	p, _ := newPlan("", nodes, opts, missing, missing, checkRel) // only stat can fail
+	return p
+}
+
+// newPlan builds a plan, looking up existing paths with stat (lstat for
+// symlinks, which must not follow an existing link) and vetting node paths
+// and symlink targets with check.
+func newPlan(rootDir string, nodes []parser.Node, opts ApplyOptions, stat, lstat func(rel string) (iofs.FileInfo, error), check func(rel string) error) (*Plan, error) {
+	p := &Plan{Version: PlanVersion, Root: rootDir, Ops: make([]Op, 0, len(nodes))}
+	planned := make(map[string]parser.NodeKind) // paths already covered by an op
+
+	// ensureDir plans dir (and its missing parents); it returns false when a
+	// file is in the way.
+	var ensureDir func(dir string, line int, mode os.FileMode) (bool, error)
+	ensureDir = func(dir string, line int, mode os.FileMode) (bool, error) {
+		if dir == "." || dir == "/" || dir == "" {
+			return true, nil
+		}
+		if kind, ok := planned[dir]; ok {
+			if kind == parser.Symlink {
+				p.Ops = append(p.Ops, Op{Kind: OpConflict, Path: dir, Reason: "a symlink in the spec is used as a directory", Line: line})
+				planned[dir] = parser.File // report it once
+			}
+			return kind == parser.Dir, nil
+		}
+		if ok, err := ensureDir(path.Dir(dir), line, 0); !ok || err != nil {
+			return ok, err
+		}
+
+		info, err := stat(dir)
+		switch {
+		case errors.Is(err, iofs.ErrNotExist):
+			p.Ops = append(p.Ops, Op{Kind: OpMkdir, Path: dir, Mode: mode, Line: line})
+		case err != nil:
+			return false, err
+		case !info.IsDir():
+			p.Ops = append(p.Ops, Op{Kind: OpConflict, Path: dir, Reason: "a file exists where a directory is needed", Line: line})
+			planned[dir] = parser.File
+			return false, nil
+		}
+		planned[dir] = parser.Dir
+		return true, nil
+	}
+
+	for _, node := range nodes {
+		node.Path = path.Clean(node.Path)
+		if !opts.AllowOutside {
+			if err := check(node.Path); err != nil {
+				p.Ops = append(p.Ops, Op{Kind: OpConflict, Path: node.Path, Reason: err.Error(), Line: node.Line})
+				planned[node.Path] = node.Kind
+				continue
+			}
+		}
+
+		if node.Kind == parser.Dir {
+			if _, err := ensureDir(node.Path, node.Line, node.Mode.Perm()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, ok := planned[node.Path]; ok {
+			continue
+		}
+		if node.Kind == parser.Symlink {
+			if !opts.AllowOutside {
+				if err := checkLink(node.Path, node.Target, check); err != nil {
+					p.Ops = append(p.Ops, Op{Kind: OpConflict, Path: node.Path, Reason: err.Error(), Line: node.Line})
+					planned[node.Path] = parser.Symlink
+					continue
+				}
+			}
+			if ok, err := ensureDir(path.Dir(node.Path), node.Line, 0); !ok || err != nil {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			planned[node.Path] = parser.Symlink
+
+			// An existing path, even a link to somewhere else, is left alone:
+			// replacing it could lose data that --force was never asked about.
+			info, err := lstat(node.Path)
+			switch {
+			case errors.Is(err, iofs.ErrNotExist):
+				p.Ops = append(p.Ops, Op{Kind: OpSymlink, Path: node.Path, Target: node.Target, Line: node.Line})
+			case err != nil:
+				return nil, err
+			case info.IsDir():
+				p.Ops = append(p.Ops, Op{Kind: OpConflict, Path: node.Path, Reason: "a directory exists where a symlink is needed", Line: node.Line})
+			default:
+				p.Ops = append(p.Ops, Op{Kind: OpSkipExists, Path: node.Path, Target: node.Target, Reason: "path exists (symlinks never replace existing paths)", Line: node.Line})
+			}
+			continue
+		}
+		ok, err := ensureDir(path.Dir(node.Path), node.Line, 0)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue // the parent conflict is already recorded
+		}
+		planned[node.Path] = parser.File
+
+		// Contents from the spec win, then a template the spec asks for,
+		// and boilerplate is the fallback. Generated contents start with the
+		// entry's description as a comment, and are executable if they are a
+		// script, unless the spec gives a mode.
+		data := node.Content
+		if data == "" && node.Template != "" {
+			data = content.GetContent(filepath.Join(filepath.Dir(targetPath(rootDir, node.Path)), node.Template))
+		}
+		if data == "" && opts.Populate {
+			data = content.GetContent(targetPath(rootDir, node.Path))
+		}
+		if opts.Populate && node.Content == "" && node.Description != "" {
+			data = content.WithHeader(node.Path, data, node.Description)
+		}
+		mode := node.Mode.Perm()
+		if mode == 0 && node.Content == "" && strings.HasPrefix(data, "#!") {
+			mode = ScriptMode
+		}
+
+		info, err := stat(node.Path)
+		switch {
+		case errors.Is(err, iofs.ErrNotExist):
+			p.Ops = append(p.Ops, Op{Kind: OpCreate, Path: node.Path, Content: data, Mode: mode, Line: node.Line})
+		case err != nil:
+			return nil, err
+		case info.IsDir():
+			p.Ops = append(p.Ops, Op{Kind: OpConflict, Path: node.Path, Reason: "a directory exists where a file is needed", Line: node.Line})
+		case opts.Force:
+			p.Ops = append(p.Ops, Op{Kind: OpOverwrite, Path: node.Path, Content: data, Mode: mode, Line: node.Line})
+		default:
+			p.Ops = append(p.Ops, Op{Kind: OpSkipExists, Path: node.Path, Reason: "file exists (use --force to overwrite)", Line: node.Line})
+		}
+	}
+	return p, nil
+}
+
+// Count returns how many ops of the given kind the plan contains.
+func (p *Plan) Count(kind OpKind) int {
+	n := 0
+	for _, op := range p.Ops {
+		if op.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary is a one-line, human-readable tally of the plan.
+func (p *Plan) Summary() string {
+	links := ""
+	if n := p.Count(OpSymlink); n > 0 {
+		links = fmt.Sprintf(", %d symlink(s) to create", n)
+	}
+	return fmt.Sprintf("Plan: %d dir(s) to create, %d file(s) to create%s, %d to overwrite, %d skipped, %d conflict(s).",
+		p.Count(OpMkdir), p.Count(OpCreate), links, p.Count(OpOverwrite), p.Count(OpSkipExists), p.Count(OpConflict))
+}
+
+// Print writes the plan in the [DRY-RUN] format used by build --dry-run.
+func (p *Plan) Print(w io.Writer) {
+	for _, op := range p.Ops {
+		switch op.Kind {
+		case OpMkdir:
+			fmt.Fprintf(w, "[DRY-RUN] Create %s/\n", op.Path)
+		case OpCreate:
+			fmt.Fprintf(w, "[DRY-RUN] Create %s\n", op.Path)
+		case OpOverwrite:
+			fmt.Fprintf(w, "[DRY-RUN] Overwrite %s\n", op.Path)
+		case OpSymlink:
+			fmt.Fprintf(w, "[DRY-RUN] Link %s -> %s\n", op.Path, op.Target)
+		case OpSkipExists:
+			fmt.Fprintf(w, "[DRY-RUN] Skip %s: %s\n", op.Path, op.Reason)
+		case OpConflict:
+			fmt.Fprintf(w, "[DRY-RUN] Conflict %s: %s\n", op.Path, op.Reason)
+		}
+	}
+	fmt.Fprintln(w, p.Summary())
+}
+
+// WritePlan serialises the plan as indented JSON.
+func WritePlan(w io.Writer, p *Plan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// ReadPlan loads a plan written by WritePlan.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	var p Plan
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+	if p.Version != PlanVersion {
+		return nil, fmt.Errorf("unsupported plan version %d (expected %d)", p.Version, PlanVersion)
+	}
+	return &p, nil
+}
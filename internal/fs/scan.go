@@ -0,0 +1,84 @@
+package fs
+
+import (
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+type ScanOptions struct {
+	MaxDepth      int      // 0 = unlimited; 1 = only the direct children of the root
+	Exclude       []string // gitignore-style globs to leave out
+	NoGitignore   bool     // do not honour .gitignore files
+	IncludeGitDir bool     // keep the .git directory (skipped by default)
+}
+
+// Scan walks rootDir and returns its contents as nodes relative to rootDir,
+// in the same flat form the parser produces. The .git and .tr2rl
+// directories are always skipped, and symlinks are listed, not followed.
+// The result can be rendered with the printer and parsed back into the same
+// node set.
+func Scan(rootDir string, opts ScanOptions) ([]parser.Node, error) {
+	nodes := make([]parser.Node, 0)
+	m := &ignoreMatcher{}
+	m.add(opts.Exclude...)
+
+	err := filepath.WalkDir(rootDir, func(p string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(rootDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "." {
+			if !opts.NoGitignore {
+				return m.load(p, "")
+			}
+			return nil
+		}
+
+		if d.IsDir() && (d.Name() == ".git" || d.Name() == StateDir) {
+			return filepath.SkipDir
+		}
+		if m.ignored(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		depth := strings.Count(rel, "/") + 1
+		if d.IsDir() {
+			nodes = append(nodes, parser.Node{Path: rel, Kind: parser.Dir})
+			if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+				return filepath.SkipDir
+			}
+			if !opts.NoGitignore {
+				return m.load(p, rel)
+			}
+			return nil
+		}
+
+		if d.Type()&iofs.ModeSymlink != 0 {
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, parser.Node{Path: rel, Kind: parser.Symlink, Target: filepath.ToSlash(target)})
+			return nil
+		}
+		nodes = append(nodes, parser.Node{Path: rel, Kind: parser.File})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
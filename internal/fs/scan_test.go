@@ -0,0 +1,91 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+func TestScan_GitignoreAndDepth(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		".gitignore":          "*.log\nbuild/\n!keep.log\n",
+		"src/main.go":         "",
+		"src/app.log":         "",
+		"src/keep.log":        "",
+		"src/deep/x/y.go":     "",
+		"src/deep/.gitignore": "secret.txt\n",
+		"src/deep/secret.txt": "",
+		"build/out.bin":       "",
+		".git/HEAD":           "",
+		"docs/guide.md":       "",
+	}
+	for p, data := range files {
+		full := filepath.Join(tmpDir, p)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(data), 0644)
+	}
+
+	nodes, err := Scan(tmpDir, ScanOptions{Exclude: []string{"docs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]parser.NodeKind)
+	for _, n := range nodes {
+		got[n.Path] = n.Kind
+	}
+
+	for _, p := range []string{"src/main.go", "src/keep.log", "src/deep/x/y.go", ".gitignore"} {
+		if got[p] != parser.File {
+			t.Errorf("expected file %s in scan result", p)
+		}
+	}
+	if got["src/deep/x"] != parser.Dir {
+		t.Errorf("expected dir src/deep/x in scan result")
+	}
+	for _, p := range []string{"src/app.log", "build", "build/out.bin", ".git", "src/deep/secret.txt", "docs"} {
+		if _, ok := got[p]; ok {
+			t.Errorf("%s should have been skipped", p)
+		}
+	}
+
+	nodes, err = Scan(tmpDir, ScanOptions{MaxDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range nodes {
+		if n.Path == "src/main.go" {
+			t.Errorf("--max-depth 1 should not descend into src/")
+		}
+	}
+}
+
+func TestScan_Symlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "shared"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "shared", "a.txt"), nil, 0644)
+	os.Symlink("shared", filepath.Join(tmpDir, "current"))
+
+	nodes, err := Scan(tmpDir, ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range nodes {
+		if n.Path == "current/a.txt" {
+			t.Error("Scan should not follow symlinks")
+		}
+		if n.Path == "current" && (n.Kind != parser.Symlink || n.Target != "shared") {
+			t.Errorf("current = %+v, want a symlink to shared", n)
+		}
+	}
+
+	report, err := Compare(tmpDir, nodes, CompareOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Drift) != 0 {
+		t.Errorf("a scan should match its own directory, got %+v", report.Drift)
+	}
+}
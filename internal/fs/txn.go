@@ -0,0 +1,201 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+)
+
+// Actions recorded by a transaction (and in the build journal).
+const (
+	actionMkdir     = "mkdir"
+	actionCreate    = "create"
+	actionOverwrite = "overwrite"
+	actionSymlink   = "symlink"
+)
+
+// txn records every change a build makes to dst so that a failure halfway
+// through can put it back the way it was.
+type txn struct {
+	dst     Dest
+	changes []change // in the order they happened
+}
+
+type change struct {
+	action string
+	path   string      // slash path relative to the root of the build
+	data   []byte      // contents written (create/overwrite), or the target of a symlink
+	old    []byte      // previous contents (overwrite)
+	mode   os.FileMode // previous permissions (overwrite)
+}
+
+// mkdirAll makes dir and its missing parents, up to the root of the build
+// ("."), and records each directory it actually creates. A new dir gets mode
+// (see setMode); its missing parents get the default. A Dest that cannot be
+// looked at only gets dir itself: plans make parents before their children.
+func (t *txn) mkdirAll(dir string, mode os.FileMode) error {
+	fsys, ok := t.dst.(FS)
+	if !ok {
+		if dir == "." {
+			return nil
+		}
+		return t.mkdir(dir, mode)
+	}
+
+	info, err := fsys.Stat(dir)
+	if err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s exists and is not a directory", dir)
+		}
+		return nil
+	}
+	if !errors.Is(err, iofs.ErrNotExist) {
+		return err
+	}
+	if dir != "." {
+		if err := t.mkdirAll(path.Dir(dir), 0); err != nil {
+			return err
+		}
+	}
+	return t.mkdir(dir, mode)
+}
+
+func (t *txn) mkdir(dir string, mode os.FileMode) error {
+	if err := t.dst.Mkdir(dir, modeOr(mode, DefaultDirMode)); err != nil {
+		return err
+	}
+	t.changes = append(t.changes, change{action: actionMkdir, path: dir})
+	return setMode(t.dst, dir, mode)
+}
+
+// mkdirParents makes the missing parents of name in an FS.
+func (t *txn) mkdirParents(name string) error {
+	if _, ok := t.dst.(FS); !ok {
+		return nil
+	}
+	return t.mkdirAll(path.Dir(name), 0)
+}
+
+// setMode gives name exactly the permission bits the spec asked for, which
+// the umask may have narrowed. A zero mode, or a Dest that stores modes as
+// given, leaves name alone.
+func setMode(dst Dest, name string, mode os.FileMode) error {
+	c, ok := dst.(chmoder)
+	if mode == 0 || !ok {
+		return nil
+	}
+	return c.Chmod(name, mode)
+}
+
+// createFile writes a new file with mode (see setMode), failing if it
+// already exists: a stale plan must not clobber a file that appeared in the
+// meantime.
+func (t *txn) createFile(name string, data []byte, mode os.FileMode) error {
+	perm := modeOr(mode, DefaultFileMode)
+	if c, ok := t.dst.(fileCreator); ok {
+		if err := c.CreateFile(name, data, perm); err != nil {
+			return err
+		}
+	} else {
+		if fsys, ok := t.dst.(FS); ok {
+			if _, err := fsys.Stat(name); err == nil {
+				return &iofs.PathError{Op: "create", Path: name, Err: iofs.ErrExist}
+			}
+		}
+		if err := t.dst.WriteFile(name, data, perm); err != nil {
+			return err
+		}
+	}
+	t.changes = append(t.changes, change{action: actionCreate, path: name, data: data})
+	return setMode(t.dst, name, mode)
+}
+
+// createSymlink makes link point at target, failing if link already exists.
+func (t *txn) createSymlink(target, link string) error {
+	l, ok := t.dst.(Linker)
+	if !ok {
+		return errors.New("the destination does not support symlinks")
+	}
+	if err := l.Symlink(target, link); err != nil {
+		return err
+	}
+	t.changes = append(t.changes, change{action: actionSymlink, path: link, data: []byte(target)})
+	return nil
+}
+
+// overwriteFile backs up the current contents and permissions of name before
+// replacing them. The file keeps its permissions unless mode is set. A Dest
+// that cannot be read back is written without a backup, and the overwrite
+// cannot be rolled back.
+func (t *txn) overwriteFile(name string, data []byte, mode os.FileMode) error {
+	fsys, isFS := t.dst.(FS)
+	r, isReader := t.dst.(reader)
+	if !isFS || !isReader {
+		return t.dst.WriteFile(name, data, modeOr(mode, DefaultFileMode))
+	}
+
+	old, err := r.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	info, err := fsys.Stat(name)
+	if err != nil {
+		return err
+	}
+	t.changes = append(t.changes, change{action: actionOverwrite, path: name, data: data, old: old, mode: info.Mode().Perm()})
+
+	if err := t.dst.WriteFile(name, data, modeOr(mode, info.Mode().Perm())); err != nil {
+		return err
+	}
+	return setMode(t.dst, name, mode)
+}
+
+// canRollback reports whether dst can take back what was written to it. An
+// archive cannot; the caller throws it away instead.
+func (t *txn) canRollback() bool {
+	_, ok := t.dst.(remover)
+	return ok
+}
+
+// rollback restores overwritten files and removes everything created, newest first.
+func (t *txn) rollback(w io.Writer) error {
+	rm, ok := t.dst.(remover)
+	if !ok {
+		return errors.New("the destination cannot remove files")
+	}
+	var errs []error
+
+	for i := len(t.changes) - 1; i >= 0; i-- {
+		c := t.changes[i]
+		if c.action == actionOverwrite {
+			if err := restoreFile(t.dst, c.path, c.old, c.mode); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore %s: %w", c.path, err))
+				continue
+			}
+			fmt.Fprintf(w, "[ROLLBACK] Restored %s\n", c.path)
+			continue
+		}
+
+		if err := rm.Remove(c.path); err != nil && !errors.Is(err, iofs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", c.path, err))
+			continue
+		}
+		fmt.Fprintf(w, "[ROLLBACK] Removed %s\n", c.path)
+	}
+
+	return errors.Join(errs...)
+}
+
+// restoreFile puts back the contents and permissions of an overwritten file.
+func restoreFile(dst Dest, name string, data []byte, mode os.FileMode) error {
+	if err := dst.WriteFile(name, data, mode); err != nil {
+		return err
+	}
+	if c, ok := dst.(chmoder); ok {
+		return c.Chmod(name, mode)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// FormatText is Result.Format for input handled by the heuristic line parser.
+const FormatText = "text"
+
+// A decoder recognises a structured listing format (the output of a tool,
+// not something typed by hand) and turns it into nodes without guessing.
+type decoder struct {
+	format string                  // reported in Result.Format
+	label  string                  // human name used in diagnostics
+	detect func(input string) bool // cheap check, no full decode
+	decode func(input string) ([]Node, error)
+}
+
+// decoders are tried in order; the first one whose detect matches wins.
+var decoders = []decoder{
+	{format: "tree-json", label: "tree -J output", detect: isTreeJSON, decode: decodeTreeJSON},
+	{format: "tree-xml", label: "tree -X output", detect: isTreeXML, decode: decodeTreeXML},
+	{format: "html", label: "HTML list", detect: isHTMLList, decode: decodeHTMLList},
+	{format: "json", label: "JSON spec", detect: isSpecJSON, decode: decodeSpecJSON},
+	{format: "tar-tv", label: "tar -tv output", detect: isTarListing, decode: decodeTarListing},
+	{format: "unzip-l", label: "unzip -l output", detect: isUnzipListing, decode: decodeUnzipListing},
+	{format: "ls-r", label: "ls -R output", detect: isLsRecursive, decode: decodeLsRecursive},
+	{format: "yaml", label: "YAML spec", detect: isSpecYAML, decode: decodeSpecYAML},
+	{format: "find", label: "find output", detect: isFind, decode: decodeFind},
+	{format: "git-ls-files", label: "git ls-files output", detect: isGitLsFiles, decode: decodeGitLsFiles},
+}
+
+// Formats lists the input formats Options.Format accepts.
+func Formats() []string {
+	names := []string{FormatText}
+	for _, d := range decoders {
+		names = append(names, d.format)
+	}
+	return names
+}
+
+// parseInput decodes structured input directly and falls back to the
+// heuristic parser for everything else. A non-empty format skips detection;
+// decoding errors are then returned instead of falling back.
+func parseInput(input, format string) (Result, error) {
+	if format == FormatText {
+		return parseText(input), nil
+	}
+	for _, d := range decoders {
+		if format != "" && d.format != format {
+			continue
+		}
+		if format == "" && !d.detect(input) {
+			continue
+		}
+		nodes, err := d.decode(input)
+		if err != nil && format != "" {
+			return Result{}, fmt.Errorf("reading input as %s: %w", d.label, err)
+		}
+		if err != nil {
+			res := parseText(input)
+			res.Diagnostics = append([]Diagnostic{{
+				Severity: SeverityWarning,
+				Code:     CodeStructuredInput,
+				Message:  fmt.Sprintf("input looks like %s but could not be decoded (%v); parsed as text", d.label, err),
+			}}, res.Diagnostics...)
+			return res, nil
+		}
+
+		res := Result{Nodes: nodes, Format: d.format}
+		res.Diagnostics = []Diagnostic{{
+			Severity: SeverityInfo,
+			Code:     CodeStructuredInput,
+			Message:  fmt.Sprintf("decoded as %s", d.label),
+		}}
+		finish(&res)
+		return res, nil
+	}
+	if format != "" {
+		return Result{}, fmt.Errorf("unknown input format %q (want one of: %s)", format, strings.Join(Formats(), ", "))
+	}
+	return parseText(input), nil
+}
+
+// FromNodes builds a Result from nodes that did not come from text, such as
+// the headers of an archive. Paths are cleaned and checked like parsed ones,
+// and directories that are only implied by their contents are added.
+func FromNodes(nodes []Node, format string) Result {
+	res := Result{Format: format}
+	res.Nodes, res.Diagnostics = sanitizePaths(nodes)
+
+	kept := res.Nodes[:0]
+	for _, n := range res.Nodes {
+		if n.Path != "." {
+			kept = append(kept, n)
+		}
+	}
+	res.Nodes = withParents(kept)
+
+	finish(&res)
+	return res
+}
+
+// joinPath appends name to dir, treating "." (the listing root) as empty.
+func joinPath(dir, name string) string {
+	name = strings.TrimSuffix(strings.ReplaceAll(name, "\\", "/"), "/")
+	if dir == "" || dir == "." {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// linkTarget tidies the target of a symlink: slash-separated and clean, as
+// tree -F or ls -F may add a trailing "/".
+func linkTarget(target string) string {
+	target = strings.TrimSpace(strings.ReplaceAll(target, "\\", "/"))
+	if target == "" {
+		return ""
+	}
+	return path.Clean(target)
+}
+
+// parseOctalMode reads a permission string such as "0644" or "755".
+func parseOctalMode(s string) (fs.FileMode, bool) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 8, 32)
+	if err != nil {
+		return 0, false
+	}
+	return fs.FileMode(v).Perm(), true
+}
+
+// parseProt reads an ls-style permission string such as "-rwxr-xr-x" or
+// "drwxr-xr-x". Only the permission bits are returned.
+func parseProt(s string) (fs.FileMode, bool) {
+	if len(s) == 10 {
+		s = s[1:]
+	}
+	if len(s) != 9 {
+		return 0, false
+	}
+	var m fs.FileMode
+	for i := 0; i < 9; i++ {
+		bit := fs.FileMode(1) << (8 - i)
+		switch c := s[i]; {
+		case c == '-':
+		case c == "rwxrwxrwx"[i]:
+			m |= bit
+		case i%3 == 2 && (c == 's' || c == 't'):
+			m |= bit // setuid/setgid/sticky on top of execute
+		case i%3 == 2 && (c == 'S' || c == 'T'):
+			// setuid/setgid/sticky without execute
+		default:
+			return 0, false
+		}
+	}
+	return m, true
+}
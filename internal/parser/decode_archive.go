@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// --- tar -tv -------------------------------------------------------------
+
+var (
+	// GNU tar: "-rw-r--r-- user/group  1234 2024-01-02 15:04 path"
+	tarGNULine = regexp.MustCompile(`^([-dlhcbps])([-rwxsStT]{9})\S*\s+\S+\s+([\d,]+)\s+\d{4}-\d{2}-\d{2}\s+\d{2}:\d{2}(?::\d{2})?\s(.+)$`)
+	// bsdtar (macOS) and ls -l: "-rw-r--r--  0 user  staff  1234 Jan  2 15:04 path"
+	tarBSDLine = regexp.MustCompile(`^([-dlhcbps])([-rwxsStT]{9})\S*\s+\d+\s+\S+\s+\S+\s+([\d,]+)\s+\w{3}\s+\d{1,2}\s+(?:\d{1,2}:\d{2}|\d{4})\s(.+)$`)
+	// The block count ls -l prints first: "total 8", "total 1.2M"
+	lsTotalLine = regexp.MustCompile(`^total \d+(?:[.,]\d+)?[KMGT]?$`)
+)
+
+// matchTarLine splits a verbose tar listing line into its type letter,
+// permission string, size and name.
+func matchTarLine(line string) (typ byte, prot, size, name string, ok bool) {
+	m := tarGNULine.FindStringSubmatch(line)
+	if m == nil {
+		m = tarBSDLine.FindStringSubmatch(line)
+	}
+	if m == nil {
+		return 0, "", "", "", false
+	}
+	return m[1][0], m[2], m[3], m[4], true
+}
+
+func isTarListing(input string) bool {
+	lines := sourceLines(input, false)
+	if len(lines) == 0 {
+		return false
+	}
+	for i, l := range lines {
+		if i == 0 && lsTotalLine.MatchString(l.text) && len(lines) > 1 {
+			continue
+		}
+		if _, _, _, _, ok := matchTarLine(l.text); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeTarListing(input string) ([]Node, error) {
+	var nodes []Node
+	for _, l := range sourceLines(input, false) {
+		typ, prot, size, name, ok := matchTarLine(l.text)
+		if !ok {
+			continue // the "total" line of ls -l
+		}
+		col := len(l.text) - len(name) + 1
+
+		kind, target := File, ""
+		switch typ {
+		case 'd':
+			kind = Dir
+		case 'l':
+			kind = Symlink
+			name, target, _ = strings.Cut(name, " -> ")
+			target = linkTarget(target)
+		case 'h':
+			name, _, _ = strings.Cut(name, " link to ")
+		case 'c', 'b', 'p', 's':
+			continue // device nodes, pipes and sockets cannot be recreated
+		}
+
+		if name = path.Clean(name); name == "." {
+			continue // "./" entry of an archive made with `tar -cf x.tar .`
+		}
+		n := Node{Path: name, Kind: kind, Target: target, Line: l.no, Column: col}
+		n.Mode, _ = parseProt(prot)
+		n.Size, _ = strconv.ParseInt(size, 10, 64)
+		nodes = append(nodes, n)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no files or directories found")
+	}
+	return withParents(nodes), nil
+}
+
+// --- unzip -l ------------------------------------------------------------
+
+var (
+	unzipHeader = regexp.MustCompile(`^\s*Length\s+Date\s+Time\s+Name\s*$`)
+	unzipEntry  = regexp.MustCompile(`^\s*(\d+)\s+\d{2,4}-\d{2}-\d{2,4}\s+\d{2}:\d{2}\s+(.+)$`)
+)
+
+func isUnzipListing(input string) bool {
+	for _, l := range sourceLines(input, false) {
+		if unzipHeader.MatchString(l.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeUnzipListing reads the entries between the two dashed rules of
+// `unzip -l` output. The listing has no modes.
+func decodeUnzipListing(input string) ([]Node, error) {
+	var nodes []Node
+	rules := 0
+	for _, l := range sourceLines(input, false) {
+		if strings.HasPrefix(strings.TrimSpace(l.text), "----") {
+			rules++
+			continue
+		}
+		if rules != 1 {
+			continue
+		}
+		m := unzipEntry.FindStringSubmatch(l.text)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: not an unzip -l entry", l.no)
+		}
+		n := Node{Path: path.Clean(m[2]), Kind: File, Line: l.no, Column: len(l.text) - len(m[2]) + 1}
+		if strings.HasSuffix(m[2], "/") {
+			n.Kind = Dir
+		}
+		n.Size, _ = strconv.ParseInt(m[1], 10, 64)
+		nodes = append(nodes, n)
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no files or directories found")
+	}
+	return withParents(nodes), nil
+}
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// isHTMLList matches a nested <ul>/<ol> list, as written by `format --to html`.
+func isHTMLList(input string) bool {
+	s := strings.ToLower(strings.TrimSpace(input))
+	return strings.HasPrefix(s, "<ul") || strings.HasPrefix(s, "<ol")
+}
+
+// decodeHTMLList reads every <li> as an entry: its own text is the name, and
+// a nested list (or a trailing slash) makes it a directory.
+func decodeHTMLList(input string) ([]Node, error) {
+	dec := xml.NewDecoder(strings.NewReader(input))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	type item struct {
+		node  *Node
+		name  strings.Builder
+		named bool // the name is complete (a nested list started)
+	}
+	var (
+		nodes []*Node
+		stack []*item // open <li> elements
+		dirs  []string
+	)
+	finishName := func(it *item) {
+		if it.named {
+			return
+		}
+		it.named = true
+		name := strings.Join(strings.Fields(it.name.String()), " ")
+		name, it.node.Mode, _ = cutMode(name)
+		if strings.HasSuffix(name, "/") {
+			it.node.Kind = Dir
+		}
+		if link, target, ok := strings.Cut(name, " -> "); ok {
+			name = link
+			it.node.Kind, it.node.Target = Symlink, linkTarget(target)
+		}
+		dir := ""
+		if len(dirs) > 0 {
+			dir = dirs[len(dirs)-1]
+		}
+		it.node.Path = joinPath(dir, name)
+	}
+
+	for {
+		line, _ := dec.InputPos()
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "li":
+				n := &Node{Kind: File, Line: line}
+				nodes = append(nodes, n)
+				stack = append(stack, &item{node: n})
+			case "ul", "ol":
+				if len(stack) > 0 {
+					it := stack[len(stack)-1]
+					finishName(it)
+					it.node.Kind = Dir
+					dirs = append(dirs, it.node.Path)
+				}
+			}
+		case xml.EndElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "li":
+				if len(stack) > 0 {
+					finishName(stack[len(stack)-1])
+					stack = stack[:len(stack)-1]
+				}
+			case "ul", "ol":
+				if len(stack) > 0 && len(dirs) > 0 {
+					dirs = dirs[:len(dirs)-1]
+				}
+			}
+		case xml.CharData:
+			if len(stack) > 0 && !stack[len(stack)-1].named {
+				stack[len(stack)-1].name.Write(t)
+			}
+		}
+	}
+
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Path != "" {
+			out = append(out, *n)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no list items found")
+	}
+	return out, nil
+}
@@ -0,0 +1,360 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// srcLine is a line of input together with its 1-based line number.
+type srcLine struct {
+	no   int
+	text string
+}
+
+// sourceLines splits input into lines, dropping trailing whitespace and
+// (unless keepBlank) empty lines.
+func sourceLines(input string, keepBlank bool) []srcLine {
+	raw := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	lines := make([]srcLine, 0, len(raw))
+	for i, l := range raw {
+		l = strings.TrimRight(l, " \t\r")
+		if l == "" && !keepBlank {
+			continue
+		}
+		lines = append(lines, srcLine{no: i + 1, text: l})
+	}
+	return lines
+}
+
+// withParents inserts a Dir node for every parent directory that is not
+// listed itself, right before the first node that needs it.
+func withParents(nodes []Node) []Node {
+	seen := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		if n.Kind == Dir {
+			seen[n.Path] = true
+		}
+	}
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		var missing []string
+		for dir := path.Dir(n.Path); dir != "." && dir != "/" && !seen[dir]; dir = path.Dir(dir) {
+			seen[dir] = true
+			missing = append(missing, dir)
+		}
+		for i := len(missing) - 1; i >= 0; i-- {
+			out = append(out, Node{Path: missing[i], Kind: Dir, Line: n.Line, Column: n.Column})
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// --- ls -R ---------------------------------------------------------------
+
+// isLsRecursive matches `ls -R` output: sections introduced by a "dir:"
+// header, the first one on the first line, the others after a blank line.
+// A lone "Something:" line is also how prose introduces a pasted tree, so
+// the first header must look like a path ("." or "./x") unless a second
+// section follows.
+func isLsRecursive(input string) bool {
+	lines := sourceLines(input, true)
+	for len(lines) > 0 && lines[0].text == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 || !isLsHeader(lines[0].text) {
+		return false
+	}
+	sections := 1
+	for i := 1; i < len(lines); i++ {
+		l := lines[i].text
+		if strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t") {
+			return false // ls never indents; this is more likely YAML or a tree
+		}
+		if hasTreeArt(l) {
+			return false
+		}
+		if isLsHeader(l) {
+			if lines[i-1].text != "" {
+				return false
+			}
+			sections++
+		}
+	}
+	return sections > 1 || isLsRootHeader(lines[0].text)
+}
+
+func isLsHeader(line string) bool {
+	return strings.HasSuffix(line, ":") && !strings.HasPrefix(line, " ") && !hasBranchMarker(line)
+}
+
+// isLsRootHeader reports whether a header names the directory the way
+// `ls -R` prints it when given "." or an explicit relative or absolute path.
+func isLsRootHeader(line string) bool {
+	dir := strings.TrimSuffix(line, ":")
+	return dir == "." || strings.HasPrefix(dir, "./") || strings.HasPrefix(dir, "/")
+}
+
+// hasTreeArt reports whether a line carries tree connectors or box-drawing
+// glyphs, which ls never prints.
+func hasTreeArt(line string) bool {
+	if hasBranchMarker(line) {
+		return true
+	}
+	return strings.IndexFunc(line, func(r rune) bool { return r >= 0x2500 && r <= 0x257F }) >= 0
+}
+
+// lsClassify lists the suffixes `ls -F` appends to names.
+const lsClassify = "/*@|=>"
+
+// lsColumns separates names when ls prints several per line.
+var lsColumns = regexp.MustCompile(`\s{2,}`)
+
+func decodeLsRecursive(input string) ([]Node, error) {
+	type entry struct {
+		path string
+		line int
+		col  int
+	}
+	var entries []entry
+	headers := make(map[string]int) // dir path -> header line
+	var order []string
+	classify := false
+
+	dir := ""
+	for _, l := range sourceLines(input, false) {
+		if isLsHeader(l.text) {
+			dir = path.Clean(strings.TrimSuffix(l.text, ":"))
+			if _, ok := headers[dir]; !ok {
+				headers[dir] = l.no
+				order = append(order, dir)
+			}
+			continue
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("line %d: entry before the first \"dir:\" header", l.no)
+		}
+		if strings.HasPrefix(l.text, "total ") {
+			continue
+		}
+		// Columns are separated by at least two spaces; piped ls prints one per line.
+		col := 1
+		for _, name := range lsColumns.Split(strings.TrimSpace(l.text), -1) {
+			name = unquoteLs(name)
+			if strings.HasSuffix(name, "/") {
+				classify = true
+			}
+			entries = append(entries, entry{path: joinPath(dir, name), line: l.no, col: col})
+			col += len(name) + 2
+		}
+	}
+
+	var nodes []Node
+	listed := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		p := e.path
+		if classify && p != "" && strings.ContainsRune(lsClassify, rune(p[len(p)-1])) {
+			p = p[:len(p)-1]
+		}
+		kind := File
+		if _, ok := headers[path.Clean(p)]; ok || strings.HasSuffix(e.path, "/") {
+			kind = Dir
+		}
+		nodes = append(nodes, Node{Path: p, Kind: kind, Line: e.line, Column: e.col})
+		listed[path.Clean(p)] = true
+	}
+	// Headers of directories not listed in any section (the argument ls -R
+	// was run with, or an empty one) still describe directories.
+	for _, dir := range order {
+		if dir != "." && !listed[dir] {
+			nodes = append(nodes, Node{Path: dir, Kind: Dir, Line: headers[dir], Column: 1})
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no files or directories found")
+	}
+	return sortByPath(withParents(nodes)), nil
+}
+
+// unquoteLs undoes the 'name with spaces' quoting of GNU ls.
+func unquoteLs(name string) string {
+	if len(name) >= 2 && name[0] == '\'' && name[len(name)-1] == '\'' {
+		return name[1 : len(name)-1]
+	}
+	return name
+}
+
+// sortByPath orders nodes so that every directory is followed by its
+// contents, keeping the input order among siblings.
+func sortByPath(nodes []Node) []Node {
+	children := make(map[string][]Node)
+	for _, n := range nodes {
+		parent := path.Dir(n.Path)
+		children[parent] = append(children[parent], n)
+	}
+	out := make([]Node, 0, len(nodes))
+	seen := make(map[string]bool, len(nodes))
+	var walk func(dir string)
+	walk = func(dir string) {
+		for _, n := range children[dir] {
+			if seen[n.Path] {
+				continue
+			}
+			seen[n.Path] = true
+			out = append(out, n)
+			if n.Kind == Dir {
+				walk(n.Path)
+			}
+		}
+	}
+	walk(".")
+	for _, n := range nodes {
+		if !seen[n.Path] { // not under "." (e.g. an absolute path)
+			seen[n.Path] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// --- find ----------------------------------------------------------------
+
+// findTyped matches `find -printf '%y %p\n'` lines such as "d ./src".
+var findTyped = regexp.MustCompile(`^([a-zA-Z]) (\..*)$`)
+
+// isFind matches `find .` output: every line is "." or starts with "./",
+// optionally after a %y type letter.
+func isFind(input string) bool {
+	lines := sourceLines(input, false)
+	if len(lines) == 0 {
+		return false
+	}
+	dotSlash := false
+	for _, l := range lines {
+		p := l.text
+		if m := findTyped.FindStringSubmatch(p); m != nil {
+			p = m[2]
+		}
+		switch {
+		case p == ".":
+		case strings.HasPrefix(p, "./"):
+			dotSlash = true
+		default:
+			return false
+		}
+	}
+	return dotSlash
+}
+
+func decodeFind(input string) ([]Node, error) {
+	var nodes []Node
+	for _, l := range sourceLines(input, false) {
+		p, kind, col := l.text, File, 1
+		if m := findTyped.FindStringSubmatch(p); m != nil {
+			p, col = m[2], 3
+			if m[1] == "d" {
+				kind = Dir
+			}
+		} else if strings.HasSuffix(p, "/") {
+			kind = Dir
+		}
+		p = path.Clean(p)
+		if p == "." {
+			continue
+		}
+		nodes = append(nodes, Node{Path: p, Kind: kind, Line: l.no, Column: col})
+	}
+
+	// Without %y, a path is a directory exactly when something is listed
+	// under it. Empty directories cannot be told apart from files.
+	parents := make(map[string]bool)
+	for _, n := range nodes {
+		for dir := path.Dir(n.Path); dir != "."; dir = path.Dir(dir) {
+			parents[dir] = true
+		}
+	}
+	for i := range nodes {
+		if parents[nodes[i].Path] {
+			nodes[i].Kind = Dir
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no files or directories found")
+	}
+	return withParents(nodes), nil
+}
+
+// --- git ls-files ------------------------------------------------------
+
+// isGitLsFiles matches `git ls-files` / `git ls-tree -r --name-only` output:
+// files only, strictly sorted by byte order, no "./" prefixes, no trailing
+// slashes, no comments, and no path that is the parent of another. A
+// hand-written path list can look just like that, and the text parser
+// handles it better (braces, modes, symlinks), so it also takes something
+// only git prints: a C-quoted path or a tracked .git* file.
+func isGitLsFiles(input string) bool {
+	lines := sourceLines(input, false)
+	if len(lines) < 2 {
+		return false
+	}
+	nested, gitSeen := false, false
+	prev := ""
+	files := make(map[string]bool, len(lines))
+	for _, l := range lines {
+		p, ok := unquoteGit(l.text)
+		if !ok || p == "" || p != strings.TrimSpace(p) ||
+			strings.HasPrefix(p, "./") || strings.HasPrefix(p, "/") || strings.HasSuffix(p, "/") ||
+			strings.HasPrefix(p, "#") || stripInlineComment(p) != p || hasBranchMarker(p) ||
+			p <= prev {
+			return false
+		}
+		// Spec syntax: a symlink arrow, a mode annotation or a brace pattern.
+		if _, mode, _ := cutMode(p); mode != 0 || strings.Contains(p, " -> ") || strings.Contains(p, "{") {
+			return false
+		}
+		if strings.HasPrefix(l.text, `"`) || strings.HasPrefix(path.Base(p), ".git") {
+			gitSeen = true
+		}
+		if strings.Contains(p, "/") {
+			nested = true
+		}
+		files[p] = true
+		prev = p
+	}
+	if !gitSeen {
+		return false
+	}
+	for p := range files {
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			if files[dir] {
+				return false
+			}
+		}
+	}
+	return nested
+}
+
+func decodeGitLsFiles(input string) ([]Node, error) {
+	var nodes []Node
+	for _, l := range sourceLines(input, false) {
+		p, ok := unquoteGit(l.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: malformed quoted path", l.no)
+		}
+		nodes = append(nodes, Node{Path: p, Kind: File, Line: l.no, Column: 1})
+	}
+	return withParents(nodes), nil
+}
+
+// unquoteGit undoes git's C-style quoting of unusual paths
+// ("caf\303\251.md" with core.quotePath).
+func unquoteGit(s string) (string, bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, true
+	}
+	u, err := strconv.Unquote(s)
+	return u, err == nil
+}
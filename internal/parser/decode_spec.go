@@ -0,0 +1,221 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Structured specs describe a tree as nested maps, in JSON or YAML:
+//
+//	{"src": {"main.go": null, "pkg": {}}, "README.md": "# Title\n"}
+//
+// A map is a directory and null is an empty file. A string is a file with
+// that content. A map made only of the keys in fileKeys is a file with
+// options. Keys ending in "/" are always directories, and lists may hold
+// file names and maps, e.g. {"src": ["a.go", "b.go"]}.
+
+type specKind int
+
+const (
+	specNull specKind = iota
+	specScalar
+	specMap
+	specList
+)
+
+// specValue is a decoded JSON or YAML value. Map keys keep their order.
+type specValue struct {
+	kind  specKind
+	str   string
+	keys  []string
+	vals  []*specValue
+	items []*specValue
+	line  int    // line of the value (maps and lists: where they start)
+	kline []int  // line of each key
+	desc  string // YAML comment on the line of the key or list item
+}
+
+// fileKeys are the keys of a file object such as {"content": "...", "mode": "0755"}.
+// An object with a "target" is a symlink, e.g. {"target": "../shared/config"}.
+var fileKeys = map[string]bool{"content": true, "mode": true, "template": true, "description": true, "target": true}
+
+func (v *specValue) isFileObject() bool {
+	if v.kind != specMap || len(v.keys) == 0 {
+		return false
+	}
+	for i, k := range v.keys {
+		if !fileKeys[k] || v.vals[i].kind != specScalar {
+			return false
+		}
+	}
+	return true
+}
+
+// walkSpec flattens a decoded spec into nodes.
+func walkSpec(root *specValue) ([]Node, error) {
+	if root.kind != specMap && root.kind != specList {
+		return nil, fmt.Errorf("line %d: a spec must be a map of names (or a list), not a single value", root.line)
+	}
+	var nodes []Node
+	var walk func(dir string, v *specValue) error
+	walk = func(dir string, v *specValue) error {
+		if v.kind == specList {
+			for _, item := range v.items {
+				switch item.kind {
+				case specScalar:
+					kind := File
+					if strings.HasSuffix(item.str, "/") {
+						kind = Dir
+					}
+					nodes = append(nodes, Node{Path: joinPath(dir, item.str), Kind: kind, Line: item.line, Description: item.desc})
+				case specMap, specList:
+					if err := walk(dir, item); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+
+		for i, name := range v.keys {
+			val, line := v.vals[i], v.kline[i]
+			forceDir := strings.HasSuffix(name, "/")
+			n := Node{Path: joinPath(dir, name), Kind: File, Line: line, Description: val.desc}
+
+			switch {
+			case val.kind == specMap && !forceDir && val.isFileObject():
+				for j, k := range val.keys {
+					s := val.vals[j].str
+					switch k {
+					case "content":
+						n.Content = s
+					case "template":
+						n.Template = s
+					case "description":
+						n.Description = s
+					case "target":
+						n.Kind, n.Target = Symlink, linkTarget(s)
+					case "mode":
+						m, ok := parseOctalMode(s)
+						if !ok {
+							m, ok = parseProt(s)
+						}
+						if !ok {
+							return fmt.Errorf("line %d: invalid mode %q for %s", val.kline[j], s, n.Path)
+						}
+						n.Mode = m
+					}
+				}
+			case val.kind == specMap || val.kind == specList:
+				n.Kind = Dir
+			case forceDir && val.kind == specScalar:
+				return fmt.Errorf("line %d: directory %s cannot have content", line, n.Path)
+			case forceDir:
+				n.Kind = Dir
+			case val.kind == specScalar:
+				n.Content = val.str
+			}
+
+			nodes = append(nodes, n)
+			if n.Kind == Dir {
+				if err := walk(n.Path, val); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk("", root); err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no files or directories found")
+	}
+	return nodes, nil
+}
+
+// --- JSON ----------------------------------------------------------------
+
+func isSpecJSON(input string) bool {
+	s := strings.TrimSpace(input)
+	return strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}")
+}
+
+func decodeSpecJSON(input string) ([]Node, error) {
+	dec := json.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+
+	// lineAt maps the decoder offset (the end of the previous token) to the
+	// line of the next token.
+	lineAt := func(off int64) int {
+		i := int(off)
+		for i < len(input) && strings.ContainsRune(" \t\r\n,:", rune(input[i])) {
+			i++
+		}
+		return strings.Count(input[:i], "\n") + 1
+	}
+
+	var read func() (*specValue, error)
+	read = func() (*specValue, error) {
+		line := lineAt(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		v := &specValue{line: line}
+		switch t := tok.(type) {
+		case nil:
+			v.kind = specNull
+		case string:
+			v.kind, v.str = specScalar, t
+		case json.Number:
+			v.kind, v.str = specScalar, t.String()
+		case bool:
+			v.kind, v.str = specScalar, strconv.FormatBool(t)
+		case json.Delim:
+			if t == '[' {
+				v.kind = specList
+				for dec.More() {
+					item, err := read()
+					if err != nil {
+						return nil, err
+					}
+					v.items = append(v.items, item)
+				}
+			} else {
+				v.kind = specMap
+				for dec.More() {
+					kline := lineAt(dec.InputOffset())
+					key, err := dec.Token()
+					if err != nil {
+						return nil, err
+					}
+					val, err := read()
+					if err != nil {
+						return nil, err
+					}
+					v.keys = append(v.keys, key.(string))
+					v.vals = append(v.vals, val)
+					v.kline = append(v.kline, kline)
+				}
+			}
+			if _, err := dec.Token(); err != nil { // closing delimiter
+				return nil, err
+			}
+		}
+		return v, nil
+	}
+
+	root, err := read()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after the top-level object")
+	}
+	return walkSpec(root)
+}
@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// treeEntry is one element of `tree -J` output. `tree -X` elements are
+// converted to the same shape so both formats share walkTree.
+type treeEntry struct {
+	Type     string          `json:"type"`
+	Name     string          `json:"name"`
+	Size     json.RawMessage `json:"size"`
+	Mode     json.RawMessage `json:"mode"`
+	Prot     string          `json:"prot"`
+	Target   string          `json:"target"`
+	Contents []treeEntry     `json:"contents"`
+}
+
+func isTreeJSON(input string) bool {
+	s := strings.TrimSpace(input)
+	return strings.HasPrefix(s, "[") && strings.Contains(s, `"type"`) && strings.Contains(s, `"name"`)
+}
+
+func decodeTreeJSON(input string) ([]Node, error) {
+	var entries []treeEntry
+	if err := json.Unmarshal([]byte(input), &entries); err != nil {
+		return nil, err
+	}
+	return walkTree(entries)
+}
+
+// xmlElement is a generic XML element; the order of children is preserved.
+type xmlElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Children []xmlElement `xml:",any"`
+}
+
+func (e xmlElement) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func isTreeXML(input string) bool {
+	s := strings.TrimSpace(input)
+	return (strings.HasPrefix(s, "<?xml") || strings.HasPrefix(s, "<tree")) && strings.Contains(s, "<tree")
+}
+
+func decodeTreeXML(input string) ([]Node, error) {
+	var root xmlElement
+	if err := xml.Unmarshal([]byte(input), &root); err != nil {
+		return nil, err
+	}
+	if root.XMLName.Local != "tree" {
+		return nil, fmt.Errorf("root element is <%s>, want <tree>", root.XMLName.Local)
+	}
+	return walkTree(xmlEntries(root.Children))
+}
+
+func xmlEntries(elems []xmlElement) []treeEntry {
+	entries := make([]treeEntry, 0, len(elems))
+	for _, e := range elems {
+		entry := treeEntry{
+			Type:     e.XMLName.Local,
+			Name:     e.attr("name"),
+			Prot:     e.attr("prot"),
+			Target:   e.attr("target"),
+			Contents: xmlEntries(e.Children),
+		}
+		if v := e.attr("size"); v != "" {
+			entry.Size = json.RawMessage(v)
+		}
+		if v := e.attr("mode"); v != "" {
+			entry.Mode = json.RawMessage(strconv.Quote(v))
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// walkTree flattens tree entries into nodes. The top-level directory is the
+// argument tree was run with: "." lists the current directory, so its
+// children become top-level nodes; any other name becomes the root node.
+// Report entries and errors are ignored.
+func walkTree(entries []treeEntry) ([]Node, error) {
+	var nodes []Node
+	var walk func(dir string, entries []treeEntry)
+	walk = func(dir string, entries []treeEntry) {
+		for _, e := range entries {
+			var kind NodeKind
+			switch e.Type {
+			case "directory":
+				kind = Dir
+			case "file":
+				kind = File
+			case "link":
+				// The contents tree -l lists for a directory link belong to
+				// its target, so they are not repeated under the link.
+				kind = Symlink
+			default:
+				continue
+			}
+			if e.Name == "" {
+				continue
+			}
+
+			p := joinPath(dir, e.Name)
+			if p != "." && p != "" {
+				n := Node{Path: p, Kind: kind}
+				if kind == Symlink {
+					n.Target = linkTarget(e.Target)
+				}
+				n.Size, _ = strconv.ParseInt(string(e.Size), 10, 64)
+				var mode string
+				if json.Unmarshal(e.Mode, &mode) == nil {
+					n.Mode, _ = parseOctalMode(mode)
+				}
+				if n.Mode == 0 && e.Prot != "" {
+					n.Mode, _ = parseProt(e.Prot)
+				}
+				nodes = append(nodes, n)
+			}
+			if kind == Dir {
+				walk(p, e.Contents)
+			}
+		}
+	}
+	walk("", entries)
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no files or directories found")
+	}
+	return nodes, nil
+}
@@ -0,0 +1,458 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The YAML decoder understands the subset of YAML a tree spec needs: block
+// maps and lists, flow maps and lists ({} and [a, b]), quoted and plain
+// scalars, block scalars (| and >) for file contents, and comments. Anchors,
+// tags and multiple documents are not supported.
+
+// yamlKeyLine matches a "key:" or "key: value" line (after indentation).
+var yamlKeyLine = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s"'#\-\[{|>][^:]*?)\s*:(\s|$)`)
+
+func isYAMLItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isSpecYAML requires a top-level map and nothing but keys and list items
+// outside of block scalars, so ordinary text trees never match.
+func isSpecYAML(input string) bool {
+	first := true
+	block := -1 // indentation of the key owning a block scalar, or -1
+	for _, l := range sourceLines(input, false) {
+		text := strings.TrimLeft(l.text, " ")
+		indent := len(l.text) - len(text)
+		if block >= 0 && indent > block {
+			continue
+		}
+		block = -1
+		if text == "---" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return false
+		}
+		if first {
+			if indent != 0 || !yamlKeyLine.MatchString(text) {
+				return false
+			}
+			first = false
+		}
+		item := isYAMLItem(text)
+		if item {
+			text = strings.TrimSpace(strings.TrimPrefix(text, "-"))
+		}
+		if m := yamlKeyLine.FindStringSubmatchIndex(text); m != nil {
+			if v := strings.TrimSpace(text[m[1]:]); strings.HasPrefix(v, "|") || strings.HasPrefix(v, ">") {
+				block = indent
+			}
+			continue
+		}
+		if !item {
+			return false
+		}
+	}
+	return !first
+}
+
+type yamlParser struct {
+	lines []string // raw lines; list items are rewritten in place
+	pos   int      // next line to read
+}
+
+func decodeSpecYAML(input string) ([]Node, error) {
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")}
+	for i, l := range p.lines {
+		if strings.TrimSpace(l) == "---" {
+			p.lines[i] = ""
+		}
+	}
+	j := p.next()
+	if j < 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+	root, err := p.parseNode(p.indent(j))
+	if err != nil {
+		return nil, err
+	}
+	if j := p.next(); j >= 0 {
+		return nil, fmt.Errorf("line %d: unexpected indentation", j+1)
+	}
+	return walkSpec(root)
+}
+
+// next returns the index of the next line with content, or -1.
+func (p *yamlParser) next() int {
+	for j := p.pos; j < len(p.lines); j++ {
+		t := strings.TrimSpace(p.lines[j])
+		if t != "" && !strings.HasPrefix(t, "#") {
+			return j
+		}
+	}
+	return -1
+}
+
+func (p *yamlParser) indent(j int) int {
+	return len(p.lines[j]) - len(strings.TrimLeft(p.lines[j], " "))
+}
+
+// text returns line j without indentation and trailing comment.
+func (p *yamlParser) text(j int) string {
+	return strings.TrimSpace(stripYAMLComment(strings.TrimLeft(p.lines[j], " ")))
+}
+
+// parseNode parses the map or list starting at the next line, which is
+// indented by ind.
+func (p *yamlParser) parseNode(ind int) (*specValue, error) {
+	j := p.next()
+	if strings.HasPrefix(strings.TrimLeft(p.lines[j], " "), "\t") {
+		return nil, fmt.Errorf("line %d: tabs cannot be used for indentation", j+1)
+	}
+	if isYAMLItem(p.text(j)) {
+		return p.parseList(ind)
+	}
+	return p.parseMap(ind)
+}
+
+func (p *yamlParser) parseMap(ind int) (*specValue, error) {
+	v := &specValue{kind: specMap, line: p.next() + 1}
+	for {
+		j := p.next()
+		if j < 0 || p.indent(j) < ind {
+			return v, nil
+		}
+		text := p.text(j)
+		if p.indent(j) > ind {
+			return nil, fmt.Errorf("line %d: unexpected indentation", j+1)
+		}
+		if isYAMLItem(text) {
+			return nil, fmt.Errorf("line %d: list item where a key was expected", j+1)
+		}
+		key, rest, ok := splitYAMLKey(text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"name:\"", j+1)
+		}
+		p.pos = j + 1
+
+		val, err := p.parseValue(ind, j, rest)
+		if err != nil {
+			return nil, err
+		}
+		val.desc = p.comment(j)
+		v.keys = append(v.keys, key)
+		v.vals = append(v.vals, val)
+		v.kline = append(v.kline, j+1)
+	}
+}
+
+func (p *yamlParser) parseList(ind int) (*specValue, error) {
+	v := &specValue{kind: specList, line: p.next() + 1}
+	for {
+		j := p.next()
+		if j < 0 || p.indent(j) < ind {
+			return v, nil
+		}
+		text := p.text(j)
+		if p.indent(j) > ind || !isYAMLItem(text) {
+			return nil, fmt.Errorf("line %d: expected a list item", j+1)
+		}
+
+		// "- name: value" starts a map; re-read the line without its dash
+		// at the indentation of the key.
+		raw := strings.TrimLeft(p.lines[j], " ")[1:]
+		item := strings.TrimLeft(raw, " ")
+		if yamlKeyLine.MatchString(item) {
+			p.lines[j] = strings.Repeat(" ", ind+1+len(raw)-len(item)) + item
+			m, err := p.parseMap(p.indent(j))
+			if err != nil {
+				return nil, err
+			}
+			v.items = append(v.items, m)
+			continue
+		}
+
+		p.pos = j + 1
+		val, err := p.parseValue(ind, j, strings.TrimSpace(strings.TrimPrefix(text, "-")))
+		if err != nil {
+			return nil, err
+		}
+		val.desc = p.comment(j)
+		v.items = append(v.items, val)
+	}
+}
+
+// parseValue parses what follows "key:" or "-" on line j: an inline value,
+// a block scalar, or a nested map or list on the following lines.
+func (p *yamlParser) parseValue(ind, j int, rest string) (*specValue, error) {
+	switch {
+	case rest == "":
+		k := p.next()
+		if k >= 0 && (p.indent(k) > ind || (p.indent(k) == ind && isYAMLItem(p.text(k)))) {
+			return p.parseNode(p.indent(k))
+		}
+		return &specValue{kind: specNull, line: j + 1}, nil
+	case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+		return &specValue{kind: specScalar, str: p.blockScalar(ind, rest), line: j + 1}, nil
+	}
+	v, err := parseYAMLFlow(rest)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %v", j+1, err)
+	}
+	setLine(v, j+1)
+	return v, nil
+}
+
+// blockScalar reads the lines of a | or > scalar owned by a key at ind.
+func (p *yamlParser) blockScalar(ind int, header string) string {
+	var body []string
+	content := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := p.lines[p.pos]
+		if strings.TrimSpace(l) == "" {
+			body = append(body, "")
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " "))
+		if n <= ind {
+			break
+		}
+		if content < 0 {
+			content = n
+		}
+		if n < content {
+			break
+		}
+		body = append(body, l[content:])
+	}
+
+	// Trailing blank lines belong to the surrounding document.
+	trail := 0
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		trail++
+	}
+
+	var s string
+	if strings.HasPrefix(header, ">") {
+		var b strings.Builder
+		for i, l := range body {
+			switch {
+			case i == 0, body[i-1] == "":
+			case l == "":
+				b.WriteString("\n")
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(l)
+		}
+		s = b.String()
+	} else {
+		s = strings.Join(body, "\n")
+	}
+
+	switch {
+	case strings.HasSuffix(header, "-"):
+	case strings.HasSuffix(header, "+"):
+		s += strings.Repeat("\n", trail+1)
+	case s != "":
+		s += "\n"
+	}
+	return s
+}
+
+func setLine(v *specValue, line int) {
+	v.line = line
+	for i := range v.kline {
+		v.kline[i] = line
+	}
+	for _, c := range v.vals {
+		setLine(c, line)
+	}
+	for _, c := range v.items {
+		setLine(c, line)
+	}
+}
+
+// splitYAMLKey splits "key: rest" and unquotes the key.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	m := yamlKeyLine.FindStringSubmatchIndex(text)
+	if m == nil {
+		return "", "", false
+	}
+	key = strings.TrimSpace(text[m[2]:m[3]])
+	if k, err := unquoteYAML(key); err == nil {
+		key = k
+	}
+	return key, strings.TrimSpace(text[m[1]:]), true
+}
+
+// stripYAMLComment removes a " # comment" that is not inside quotes.
+func stripYAMLComment(s string) string {
+	s, _ = splitYAMLComment(s)
+	return s
+}
+
+// splitYAMLComment splits s at a " # comment" that is not inside quotes and
+// returns the text before it and the comment without its "#".
+func splitYAMLComment(s string) (text, comment string) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i], strings.TrimSpace(strings.TrimLeft(s[i:], "#"))
+		}
+	}
+	return s, ""
+}
+
+// comment returns the trailing comment of line j, if any.
+func (p *yamlParser) comment(j int) string {
+	_, c := splitYAMLComment(strings.TrimLeft(p.lines[j], " "))
+	return c
+}
+
+// unquoteYAML undoes "double" (with escapes) or 'single' (” escapes) quoting.
+func unquoteYAML(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return "", fmt.Errorf("not quoted")
+}
+
+// parseYAMLFlow parses an inline value: a scalar, {...} or [...].
+func parseYAMLFlow(s string) (*specValue, error) {
+	f := &yamlFlow{s: s}
+	v, err := f.value(false)
+	if err != nil {
+		return nil, err
+	}
+	if f.skip(); f.pos < len(f.s) {
+		return nil, fmt.Errorf("unexpected %q after value", f.s[f.pos:])
+	}
+	return v, nil
+}
+
+type yamlFlow struct {
+	s   string
+	pos int
+}
+
+func (f *yamlFlow) skip() {
+	for f.pos < len(f.s) && (f.s[f.pos] == ' ' || f.s[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+// value parses one value; inside a collection plain scalars end at , ] } or :.
+func (f *yamlFlow) value(nested bool) (*specValue, error) {
+	f.skip()
+	if f.pos >= len(f.s) {
+		return &specValue{kind: specNull}, nil
+	}
+	switch f.s[f.pos] {
+	case '{':
+		return f.collection('}')
+	case '[':
+		return f.collection(']')
+	case '"':
+		q, err := strconv.QuotedPrefix(f.s[f.pos:])
+		if err != nil {
+			return nil, err
+		}
+		f.pos += len(q)
+		s, err := strconv.Unquote(q)
+		return &specValue{kind: specScalar, str: s}, err
+	case '\'':
+		end := f.pos + 1
+		for ; end < len(f.s); end++ {
+			if f.s[end] == '\'' {
+				if end+1 < len(f.s) && f.s[end+1] == '\'' {
+					end++
+					continue
+				}
+				break
+			}
+		}
+		if end >= len(f.s) {
+			return nil, fmt.Errorf("unterminated quote")
+		}
+		s := strings.ReplaceAll(f.s[f.pos+1:end], "''", "'")
+		f.pos = end + 1
+		return &specValue{kind: specScalar, str: s}, nil
+	}
+
+	start := f.pos
+	for f.pos < len(f.s) {
+		c := f.s[f.pos]
+		if nested && (c == ',' || c == ']' || c == '}' || (c == ':' && (f.pos+1 == len(f.s) || f.s[f.pos+1] == ' '))) {
+			break
+		}
+		f.pos++
+	}
+	s := strings.TrimSpace(f.s[start:f.pos])
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return &specValue{kind: specNull}, nil
+	}
+	return &specValue{kind: specScalar, str: s}, nil
+}
+
+func (f *yamlFlow) collection(end byte) (*specValue, error) {
+	f.pos++ // opening bracket
+	v := &specValue{kind: specList}
+	if end == '}' {
+		v.kind = specMap
+	}
+	for {
+		f.skip()
+		if f.pos >= len(f.s) {
+			return nil, fmt.Errorf("missing %q", end)
+		}
+		if f.s[f.pos] == end {
+			f.pos++
+			return v, nil
+		}
+
+		item, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		if end == '}' {
+			if f.skip(); f.pos >= len(f.s) || f.s[f.pos] != ':' {
+				return nil, fmt.Errorf("expected \":\" after key %q", item.str)
+			}
+			f.pos++
+			val, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			v.keys = append(v.keys, item.str)
+			v.vals = append(v.vals, val)
+			v.kline = append(v.kline, 0)
+		} else {
+			v.items = append(v.items, item)
+		}
+
+		f.skip()
+		if f.pos < len(f.s) && f.s[f.pos] == ',' {
+			f.pos++
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package parser
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxExpansion caps how many paths one brace pattern may produce. Larger
+// patterns are kept literally with a warning rather than flooding the tree.
+const maxExpansion = 10000
+
+// expandNodes applies shell-style brace expansion to every node path. A
+// directory whose name expands is repeated with all of its children, because
+// child paths carry the unexpanded parent name.
+func expandNodes(nodes []Node) ([]Node, []Diagnostic) {
+	var diags []Diagnostic
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		paths, ok := expandBraces(n.Path)
+		if !ok {
+			diags = append(diags, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     CodeBraceExpansion,
+				Line:     n.Line,
+				Message:  fmt.Sprintf("%q expands to more than %d paths; kept as written", unescapeName(n.Path), maxExpansion),
+			})
+		}
+		for _, p := range paths {
+			if p = cleanExpanded(n.Path, p); p == "" {
+				continue
+			}
+			e := n
+			e.Path = p
+			out = append(out, e)
+		}
+	}
+	return out, diags
+}
+
+// cleanExpanded tidies a path made by expansion: an empty alternative such
+// as "{,test}/a" must not leave a stray slash behind.
+func cleanExpanded(pattern, p string) string {
+	if p == pattern {
+		return p
+	}
+	p = path.Clean(p)
+	if !strings.HasPrefix(pattern, "/") {
+		p = strings.TrimLeft(p, "/")
+	}
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+// expandBraces expands "{a,b}" alternatives and "{1..9}", "{01..10..2}" or
+// "{a..e}" ranges the way a shell does: nested braces expand inside out,
+// escaped characters (see unescapeName) are literal, and a brace pair
+// without a top-level comma or a valid range (such as "{{name}}" or
+// "${VAR}") is kept as written. ok is false, and s is returned unexpanded,
+// if the pattern is too large.
+func expandBraces(s string) (paths []string, ok bool) {
+	out, ok := expand(s)
+	if !ok {
+		out = []string{s}
+	}
+	for i := range out {
+		out[i] = unescapeName(out[i])
+	}
+	return out, ok
+}
+
+// expand works on escaped text; the caller unescapes the results once.
+func expand(s string) ([]string, bool) {
+	for start := 0; start < len(s); start++ {
+		switch {
+		case s[start] == '\\':
+			start++
+			continue
+		case s[start] != '{' || (start > 0 && s[start-1] == '$'):
+			continue
+		}
+		end := matchBrace(s, start)
+		if end < 0 {
+			continue
+		}
+		alts, ok := alternatives(s[start+1 : end])
+		if !ok {
+			continue // not an expansion; look for one further on
+		}
+		if alts == nil {
+			return nil, false // a range too large to list
+		}
+
+		prefix, suffix := s[:start], s[end+1:]
+		rest, ok := expand(suffix)
+		if !ok {
+			return nil, false
+		}
+		var out []string
+		for _, alt := range alts {
+			inner, ok := expand(alt)
+			if !ok || len(out)+len(inner)*len(rest) > maxExpansion {
+				return nil, false
+			}
+			for _, a := range inner {
+				for _, r := range rest {
+					out = append(out, prefix+a+r)
+				}
+			}
+		}
+		return out, true
+	}
+	return []string{s}, true
+}
+
+// matchBrace returns the index of the "}" closing the "{" at start, or -1.
+func matchBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// alternatives splits the inside of a brace pair at its top-level commas,
+// or expands it as a range. ok is false if it is neither; alts is nil for a
+// range of more than maxExpansion values.
+func alternatives(body string) ([]string, bool) {
+	var alts []string
+	depth, last := 0, 0
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alts = append(alts, strings.TrimSpace(body[last:i]))
+				last = i + 1
+			}
+		}
+	}
+	if alts != nil {
+		return append(alts, strings.TrimSpace(body[last:])), true
+	}
+	return expandRange(body)
+}
+
+var (
+	numRange  = regexp.MustCompile(`^(-?\d+)\.\.(-?\d+)(?:\.\.(-?\d+))?$`)
+	charRange = regexp.MustCompile(`^([a-zA-Z])\.\.([a-zA-Z])(?:\.\.(-?\d+))?$`)
+)
+
+// expandRange expands "1..9", "01..10" (zero-padded to the wider end),
+// "1..10..2" and "a..e". The range runs downwards if the end is smaller.
+func expandRange(body string) ([]string, bool) {
+	var from, to, width int
+	var letters bool
+	var m []string
+	if m = numRange.FindStringSubmatch(body); m != nil {
+		var err1, err2 error
+		from, err1 = strconv.Atoi(m[1])
+		to, err2 = strconv.Atoi(m[2])
+		if err1 != nil || err2 != nil {
+			return nil, false
+		}
+		if zeroPadded(m[1]) || zeroPadded(m[2]) {
+			width = max(len(m[1]), len(m[2]))
+		}
+	} else if m = charRange.FindStringSubmatch(body); m != nil {
+		from, to, letters = int(m[1][0]), int(m[2][0]), true
+	} else {
+		return nil, false
+	}
+
+	step := 1
+	if m[3] != "" {
+		n, err := strconv.Atoi(m[3])
+		if err != nil {
+			return nil, false
+		}
+		step = max(n, -n, 1)
+	}
+	count := (max(from, to)-min(from, to))/step + 1
+	if count > maxExpansion {
+		return nil, true
+	}
+	if to < from {
+		step = -step
+	}
+
+	out := make([]string, 0, count)
+	for i, v := 0, from; i < count; i, v = i+1, v+step {
+		switch {
+		case letters:
+			out = append(out, string(rune(v)))
+		case width > 0:
+			out = append(out, padInt(v, width))
+		default:
+			out = append(out, strconv.Itoa(v))
+		}
+	}
+	return out, true
+}
+
+func zeroPadded(n string) bool {
+	n = strings.TrimPrefix(n, "-")
+	return len(n) > 1 && n[0] == '0'
+}
+
+// padInt formats v with leading zeros to width characters, sign included.
+func padInt(v, width int) string {
+	if v < 0 {
+		return "-" + fmt.Sprintf("%0*d", width-1, -v)
+	}
+	return fmt.Sprintf("%0*d", width, v)
+}
+
+// unescapeName turns the escaped form used between the scanner and
+// expandBraces ("\{", "\}", "\," and "\\" for literal characters) back into
+// plain text.
+func unescapeName(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`{},\`, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
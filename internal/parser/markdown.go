@@ -0,0 +1,274 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Block is a fenced code block (``` or ~~~) found in Markdown input,
+// such as a chat transcript that wraps a tree in a code fence.
+type Block struct {
+	Lang      string // First word of the info string ("text", "bash", ...)
+	Info      string // Full info string after the fence
+	Body      string // Lines between the fences
+	StartLine int    // 1-based line of the first body line
+	EndLine   int    // 1-based line of the last body line
+}
+
+// FindBlocks returns every fenced code block in input, in order.
+// An unterminated fence runs to the end of the input, as in CommonMark.
+func FindBlocks(input string) []Block {
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	blocks := make([]Block, 0)
+
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := openFence(lines[i])
+		if !ok {
+			continue
+		}
+
+		b := Block{Info: info, StartLine: i + 2}
+		if fields := strings.Fields(info); len(fields) > 0 {
+			b.Lang = strings.ToLower(fields[0])
+		}
+
+		body := make([]string, 0)
+		j := i + 1
+		for ; j < len(lines); j++ {
+			if closesFence(lines[j], fence) {
+				break
+			}
+			body = append(body, lines[j])
+		}
+		b.Body = strings.Join(body, "\n")
+		b.EndLine = i + 1 + len(body)
+		blocks = append(blocks, b)
+		i = j
+	}
+	return blocks
+}
+
+// openFence reports whether line opens a fence and returns the fence run
+// (e.g. "````") along with the info string.
+func openFence(line string) (fence, info string, ok bool) {
+	trim := strings.TrimLeft(line, " ")
+	if len(line)-len(trim) > 3 || len(trim) < 3 {
+		return "", "", false
+	}
+	ch := trim[0]
+	if ch != '`' && ch != '~' {
+		return "", "", false
+	}
+	n := 0
+	for n < len(trim) && trim[n] == ch {
+		n++
+	}
+	if n < 3 {
+		return "", "", false
+	}
+	info = strings.TrimSpace(trim[n:])
+	// Backtick fences may not carry backticks in their info string.
+	if ch == '`' && strings.Contains(info, "`") {
+		return "", "", false
+	}
+	return trim[:n], info, true
+}
+
+func closesFence(line, fence string) bool {
+	trim := strings.TrimSpace(line)
+	if !strings.HasPrefix(trim, fence) {
+		return false
+	}
+	return strings.Trim(trim, fence[:1]) == ""
+}
+
+// codeLangs are fence languages that almost never hold a directory tree.
+var codeLangs = map[string]bool{
+	"go": true, "python": true, "py": true, "js": true, "javascript": true,
+	"ts": true, "typescript": true, "tsx": true, "jsx": true, "java": true,
+	"c": true, "cpp": true, "rust": true, "rs": true, "ruby": true, "php": true,
+	"html": true, "css": true, "sql": true, "json": true, "yaml": true, "yml": true,
+	"toml": true, "xml": true, "dockerfile": true, "makefile": true, "kotlin": true,
+}
+
+// treeScore rates how much a block looks like a directory tree.
+// Positive scores are tree-like; zero or less means "probably code".
+func treeScore(b Block) int {
+	score := 0
+	for _, l := range ScanLines(b.Body) {
+		switch {
+		case l.IsComment || l.CleanName == "":
+		case l.Marker != "":
+			score += 3
+		case l.IsDir || l.IsPathLike || looksLikeFile(l.CleanName):
+			score++
+		case strings.ContainsAny(l.CleanName, "(){};=\"'<>"):
+			score -= 2
+		default:
+			score--
+		}
+	}
+	if codeLangs[b.Lang] {
+		score -= 5
+	}
+	return score
+}
+
+// pickBlock returns the index of the most tree-like block, or -1 if none qualifies.
+func pickBlock(blocks []Block) int {
+	best, bestScore := -1, 0
+	for i, b := range blocks {
+		if s := treeScore(b); s > bestScore {
+			best, bestScore = i, s
+		}
+	}
+	return best
+}
+
+// isolateBlock blanks every line outside b so that line numbers in the
+// parsed result still point into the original input.
+func isolateBlock(input string, b Block) string {
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	for i := range lines {
+		if i+1 < b.StartLine || i+1 > b.EndLine {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// captionPath extracts a file path from a heading or caption line that
+// introduces a code block, e.g. "### src/main.go", "**`app.py`**:" or
+// "Create `src/util.go` with:". It returns "" when the line names no file.
+func captionPath(line string) string {
+	s := strings.TrimSpace(line)
+	s = strings.TrimLeft(s, "#")
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "- ")
+	s = strings.TrimPrefix(s, "* ")
+	if i := strings.Index(s, ". "); i > 0 && i <= 3 && strings.Trim(s[:i], "0123456789") == "" {
+		s = s[i+2:] // numbered list "1. "
+	}
+
+	// A backtick span wins: "Create `src/main.go` with:"
+	if i := strings.Index(s, "`"); i >= 0 {
+		if j := strings.Index(s[i+1:], "`"); j > 0 {
+			if p := cleanCaptionToken(s[i+1 : i+1+j]); p != "" {
+				return p
+			}
+		}
+	}
+
+	s = strings.NewReplacer("**", "", "__", "", "`", "").Replace(s)
+	for _, prefix := range []string{"File:", "file:", "Filename:", "filename:", "Path:", "path:"} {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	// Drop a trailing remark like "(entry point)".
+	if i := strings.Index(s, " ("); i > 0 {
+		s = s[:i]
+	}
+	return cleanCaptionToken(s)
+}
+
+func cleanCaptionToken(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, ":")
+	s = strings.TrimPrefix(s, "./")
+	s = strings.ReplaceAll(s, "\\", "/")
+	if s == "" || strings.ContainsAny(s, " \t") || strings.HasSuffix(s, "/") {
+		return ""
+	}
+	if !strings.Contains(s, "/") && !looksLikeFile(s) {
+		return ""
+	}
+	return s
+}
+
+// blockPath finds the file a content block belongs to: an explicit
+// title="..." or "lang:path" in the info string, otherwise the closest
+// non-blank line above the opening fence.
+func blockPath(lines []string, b Block) string {
+	if i := strings.Index(b.Info, `title="`); i >= 0 {
+		rest := b.Info[i+len(`title="`):]
+		if j := strings.Index(rest, `"`); j >= 0 {
+			return cleanCaptionToken(rest[:j])
+		}
+	}
+	if fields := strings.Fields(b.Info); len(fields) > 0 {
+		if i := strings.Index(fields[0], ":"); i > 0 {
+			if p := cleanCaptionToken(fields[0][i+1:]); p != "" {
+				return p
+			}
+		}
+	}
+
+	// b.StartLine-1 is the fence itself (1-based), so the caption search starts above it.
+	for i := b.StartLine - 3; i >= 0 && i >= b.StartLine-5; i-- {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		return captionPath(lines[i])
+	}
+	return ""
+}
+
+// attachContents links the captioned code blocks of a Markdown answer to the
+// matching file nodes, storing the block text as the node content. skip is
+// the index of the block holding the tree itself.
+func attachContents(input string, blocks []Block, skip int, nodes []Node) []Diagnostic {
+	var diags []Diagnostic
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+
+	for i, b := range blocks {
+		if i == skip {
+			continue
+		}
+		p := blockPath(lines, b)
+		if p == "" {
+			continue
+		}
+
+		matches := make([]int, 0, 1)
+		for j, n := range nodes {
+			if n.Kind != File {
+				continue
+			}
+			if n.Path == p {
+				matches = []int{j}
+				break
+			}
+			if strings.HasSuffix(n.Path, "/"+p) {
+				matches = append(matches, j)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			diags = append(diags, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     CodeUnmatchedContent,
+				Line:     b.StartLine - 1,
+				Message:  fmt.Sprintf("code block for %q matches no file in the tree", p),
+			})
+		case 1:
+			n := &nodes[matches[0]]
+			if b.Body != "" {
+				n.Content = b.Body + "\n"
+			}
+			diags = append(diags, Diagnostic{
+				Severity: SeverityInfo,
+				Code:     CodeInlineContent,
+				Line:     b.StartLine - 1,
+				Message:  fmt.Sprintf("contents of %q taken from lines %d-%d", n.Path, b.StartLine, b.EndLine),
+			})
+		default:
+			diags = append(diags, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     CodeUnmatchedContent,
+				Line:     b.StartLine - 1,
+				Message:  fmt.Sprintf("code block for %q matches %d files; use the full path in the heading", p, len(matches)),
+			})
+		}
+	}
+	return diags
+}
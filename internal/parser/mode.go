@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+var (
+	// A permission prefix: "-rwxr-xr-x deploy.sh" (ls -l style), or
+	// "[-rwxr-xr-x]  deploy.sh" and "[drwxr-xr-x  4096]  src" from tree -p
+	// (with -s, -u or -g the brackets hold more fields), or "[755] deploy.sh".
+	modePrefix = regexp.MustCompile(`^(?:\[([-dl]?[-rwxsStT]{9}|[0-7]{3,4})(?:\s[^\]]*)?\]|([-dl][-rwxsStT]{9})[.+@]?)\s+`)
+	// A permission suffix: "deploy.sh [755]", "deploy.sh [rwxr-xr-x]".
+	modeSuffix = regexp.MustCompile(`\s\[([-dl]?[-rwxsStT]{9}|[0-7]{3,4})\](\s|$)`)
+)
+
+// cutMode takes a permission annotation off a spec line and returns the line
+// without it, the permission bits, and whether an ls-style type letter said
+// the entry is a directory. The suffix form must end the name: only a
+// symlink arrow or a comment may follow it.
+func cutMode(s string) (rest string, mode fs.FileMode, dir bool) {
+	if m := modePrefix.FindStringSubmatchIndex(s); m != nil {
+		var prot string
+		if m[2] >= 0 {
+			prot = s[m[2]:m[3]]
+		} else {
+			prot = s[m[4]:m[5]]
+		}
+		if mode, ok := readMode(prot); ok {
+			return s[m[1]:], mode, prot[0] == 'd'
+		}
+	}
+
+	for _, m := range modeSuffix.FindAllStringSubmatchIndex(s, -1) {
+		after := strings.TrimSpace(s[m[1]:])
+		if after != "" && !strings.HasPrefix(after, "-> ") && !startsComment(after) {
+			continue
+		}
+		if mode, ok := readMode(s[m[2]:m[3]]); ok {
+			return s[:m[0]] + s[m[1]-len(s[m[4]:m[5]]):], mode, false
+		}
+	}
+	return s, 0, false
+}
+
+// readMode reads "755", "0755", "rwxr-xr-x" or "-rwxr-xr-x".
+func readMode(s string) (fs.FileMode, bool) {
+	if s[0] >= '0' && s[0] <= '7' {
+		return parseOctalMode(s)
+	}
+	return parseProt(s)
+}
+
+func startsComment(s string) bool {
+	for _, m := range commentMarkers {
+		if strings.HasPrefix(s, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatMode returns the annotation a spec line carries for mode, such as
+// "[755]", or "" for 0 (no mode given).
+func FormatMode(mode fs.FileMode) string {
+	if mode.Perm() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[%03o]", uint32(mode.Perm()))
+}
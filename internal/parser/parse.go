@@ -6,13 +6,73 @@
 package parser
 
 import (
+	"fmt"
 	"path"
 	"strings"
 )
 
+// Options tunes how input is interpreted before parsing.
+type Options struct {
+	// Block selects the 1-based fenced code block to parse when the input is
+	// Markdown (e.g. a pasted chat answer). 0 picks the most tree-like block.
+	Block int
+
+	// Format forces an input format (see Formats), e.g. "yaml" for a file
+	// named spec.yaml. Empty means detect it from the content.
+	Format string
+}
+
 // Parse turns a text tree (Windows ASCII tree, Unicode tree, indented lists, mixed)
 // into a flat list of Nodes with normalized slash-separated paths.
 func Parse(input string) Result {
+	res, _ := ParseWithOptions(input, Options{})
+	return res
+}
+
+// ParseWithOptions is Parse with explicit options. It only fails when the
+// options cannot be satisfied, e.g. a --block index that does not exist.
+func ParseWithOptions(input string, opts Options) (Result, error) {
+	var pre []Diagnostic
+	original := input
+	blocks := FindBlocks(input)
+	treeIdx := -1
+
+	// Markdown pre-pass: narrow the input down to a single fenced block.
+	if len(blocks) > 0 || opts.Block > 0 {
+		idx := opts.Block - 1
+		if opts.Block == 0 {
+			idx = pickBlock(blocks)
+		} else if opts.Block > len(blocks) {
+			return Result{}, fmt.Errorf("block %d requested but input has %d fenced code block(s)", opts.Block, len(blocks))
+		}
+		if idx >= 0 {
+			treeIdx = idx
+			b := blocks[idx]
+			input = isolateBlock(input, b)
+			pre = append(pre, Diagnostic{
+				Severity: SeverityInfo,
+				Code:     CodeMarkdownBlock,
+				Line:     b.StartLine,
+				Message:  fmt.Sprintf("using fenced block %d of %d (lines %d-%d)", idx+1, len(blocks), b.StartLine, b.EndLine),
+			})
+		}
+	}
+
+	res, err := parseInput(input, opts.Format)
+	if err != nil {
+		return Result{}, err
+	}
+	res.Diagnostics = append(pre, res.Diagnostics...)
+
+	// The remaining blocks may carry the contents of files in the tree.
+	if treeIdx >= 0 && len(blocks) > 1 {
+		res.Diagnostics = append(res.Diagnostics, attachContents(original, blocks, treeIdx, res.Nodes)...)
+	}
+	return res, nil
+}
+
+// parseText runs the heuristic line-based parser over plain text.
+func parseText(input string) Result {
 	lines := ScanLines(input)
 
 	// Pre-filter lines to remove comments and junk
@@ -51,7 +111,8 @@ func Parse(input string) Result {
 	}
 
 	result := Result{
-		Warnings: make([]string, 0),
+		Diagnostics: make([]Diagnostic, 0),
+		Format:      FormatText,
 	}
 
 	// Phase 1: Heuristic Analysis
@@ -73,55 +134,154 @@ func Parse(input string) Result {
 		// result.Warnings = append(result.Warnings, "Detected Path List format")
 	}
 
+	var diags []Diagnostic
 	if isPathList {
 		result.Nodes = parsePathList(lines)
 	} else {
-		result.Nodes, result.Warnings = parseTree(lines, result.Warnings, markerCount == 0)
+		result.Nodes, diags = parseTree(lines, markerCount == 0)
+		result.Diagnostics = append(result.Diagnostics, diags...)
 	}
 
-	// Normalize Output
-	norm := make([]string, 0, len(result.Nodes))
-	for _, n := range result.Nodes {
+	// Brace patterns expand last, so that children listed under a
+	// "{api,web}/" directory end up in each of its copies.
+	result.Nodes, diags = expandNodes(result.Nodes)
+	result.Diagnostics = append(result.Diagnostics, diags...)
+
+	finish(&result)
+	return result
+}
+
+// finish cleans up parsed nodes (relative paths, no duplicates) and fills in
+// Result.Normalized. Every parsing strategy ends here.
+func finish(result *Result) {
+	var diags []Diagnostic
+	result.Nodes, diags = sanitizePaths(result.Nodes)
+	result.Diagnostics = append(result.Diagnostics, diags...)
+
+	result.Nodes, diags = dedupeNodes(result.Nodes)
+	result.Diagnostics = append(result.Diagnostics, diags...)
+
+	result.Normalized = Normalize(result.Nodes)
+}
+
+// Normalize renders nodes as a path list, one per line, with a trailing
+// slash on directories and " -> target" after symlinks. This is the format
+// of Result.Normalized.
+func Normalize(nodes []Node) string {
+	norm := make([]string, 0, len(nodes))
+	for _, n := range nodes {
 		p := n.Path
 		if n.Kind == Dir && !strings.HasSuffix(p, "/") {
 			p += "/"
 		}
+		if n.Kind == Symlink {
+			p += " -> " + n.Target
+		}
 		norm = append(norm, p)
 	}
-	result.Normalized = strings.Join(norm, "\n")
-
-	return result
+	return strings.Join(norm, "\n")
 }
 
 func parsePathList(lines []LineInfo) []Node {
 	nodes := make([]Node, 0, len(lines))
-	seen := make(map[string]bool)
 
 	for _, l := range lines {
-		clean := strings.TrimSpace(l.Raw) // Use raw for path lists, but trim
+		raw, mode, modeDir := cutMode(strings.TrimSpace(l.Raw)) // Use raw for path lists, but trim
+		clean, target, _, _ := splitEntry(raw)
 		// Remove ./ prefix if present
 		clean = strings.TrimPrefix(clean, "./")
-		clean = strings.ReplaceAll(clean, "\\", "/")
 
 		if clean == "" {
 			continue
 		}
 
 		kind := File
+		if modeDir {
+			kind = Dir
+		}
 		if strings.HasSuffix(clean, "/") {
 			kind = Dir
 			clean = strings.TrimSuffix(clean, "/")
 		}
-
-		if !seen[clean] {
-			nodes = append(nodes, Node{Path: clean, Kind: kind})
-			seen[clean] = true
+		if target != "" {
+			kind = Symlink
 		}
+
+		nodes = append(nodes, Node{Path: clean, Kind: kind, Target: linkTarget(target), Mode: mode, Line: l.LineNo, Column: l.Column, Description: l.Description})
 	}
 	return nodes
 }
 
-func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]Node, []string) {
+// sanitizePaths makes every node path relative and clean. Absolute paths
+// ("/etc/nginx", "C:/src") are rewritten to relative ones; paths that climb out
+// of the root with ".." are kept but flagged, so the build step can refuse them.
+func sanitizePaths(nodes []Node) ([]Node, []Diagnostic) {
+	var diags []Diagnostic
+	for i := range nodes {
+		p := nodes[i].Path
+		if len(p) >= 3 && p[1] == ':' && p[2] == '/' {
+			p = p[3:] // drive letter
+		}
+
+		if strings.HasPrefix(p, "/") {
+			p = strings.TrimLeft(p, "/")
+			if p == "" {
+				p = "."
+			}
+			diags = append(diags, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     CodeAbsolutePath,
+				Line:     nodes[i].Line,
+				Message:  fmt.Sprintf("absolute path %q rewritten as %q", nodes[i].Path, p),
+			})
+		}
+
+		p = path.Clean(p)
+		if p == ".." || strings.HasPrefix(p, "../") {
+			diags = append(diags, Diagnostic{
+				Severity: SeverityError,
+				Code:     CodeOutsideRoot,
+				Line:     nodes[i].Line,
+				Message:  fmt.Sprintf("%q climbs out of the output directory", p),
+			})
+		}
+		nodes[i].Path = p
+	}
+	return nodes, diags
+}
+
+// dedupeNodes keeps the first occurrence of every path. If any occurrence is a
+// directory, the surviving node becomes a directory as well.
+func dedupeNodes(nodes []Node) ([]Node, []Diagnostic) {
+	var diags []Diagnostic
+	out := make([]Node, 0, len(nodes))
+	first := make(map[string]int, len(nodes)) // path -> index in out
+
+	for _, n := range nodes {
+		idx, ok := first[n.Path]
+		if !ok {
+			first[n.Path] = len(out)
+			out = append(out, n)
+			continue
+		}
+		if n.Kind == Dir {
+			out[idx].Kind = Dir
+			out[idx].Target = ""
+		}
+		if out[idx].Description == "" {
+			out[idx].Description = n.Description
+		}
+		diags = append(diags, Diagnostic{
+			Severity: SeverityWarning,
+			Code:     CodeDuplicatePath,
+			Line:     n.Line,
+			Message:  fmt.Sprintf("duplicate path %q (first declared on line %d)", n.Path, out[idx].Line),
+		})
+	}
+	return out, diags
+}
+
+func parseTree(lines []LineInfo, indentedListMode bool) ([]Node, []Diagnostic) {
 	// Root Handling: Check if first line is a root
 	// A line is ROOT if:
 	// 1. Depth is 0 (or very low compared to next)
@@ -130,6 +290,7 @@ func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]No
 
 	nodes := make([]Node, 0, len(lines))
 	stack := make([]string, 0, 32)
+	var diags []Diagnostic
 
 	// Heuristic: If first line has markers, it's probably NOT a root (it's a child of CWD)
 	// But if first line has NO markers, and second line DOES, first line is Root.
@@ -148,6 +309,17 @@ func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]No
 				if l1.Indent >= l0.Indent || l1.Marker != "" {
 					rootIdx = 0
 				}
+				// In an indented list the root must wrap everything: a later
+				// line back at (or left of) its indentation is a sibling,
+				// as in "src/\n  main.go\ndocs/".
+				if indentedListMode && rootIdx == 0 {
+					for _, l := range lines[1:] {
+						if l.Indent <= l0.Indent {
+							rootIdx = -1
+							break
+						}
+					}
+				}
 			} else if len(lines) == 1 && isDirLike(lines[0].CleanName) {
 				// Single line directory
 				rootIdx = 0
@@ -167,7 +339,7 @@ func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]No
 		rootName := strings.TrimSuffix(lines[0].CleanName, "/")
 		stack = append(stack, rootName)
 		indentStack = append(indentStack, lines[0].Indent)
-		nodes = append(nodes, Node{Path: rootName, Kind: Dir})
+		nodes = append(nodes, Node{Path: rootName, Kind: Dir, Line: lines[0].LineNo, Column: lines[0].Column, Description: lines[0].Description})
 		// Start processing children from index 1
 		lines = lines[1:]
 	} else {
@@ -188,7 +360,13 @@ func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]No
 		// 2. Junk Filter
 		// EXCEPTION: If it has a valid marker, TRUST IT.
 		// EXCEPTION: If we are in Indented List Mode (no markers at all), TRUST IT.
-		if !indentedListMode && l.Marker == "" && !l.IsPathLike && strings.Contains(name, " ") && !looksLikeFile(name) {
+		if !indentedListMode && l.Marker == "" && !l.IsPathLike && !l.Quoted && strings.Contains(name, " ") && !looksLikeFile(name) {
+			diags = append(diags, Diagnostic{
+				Severity: SeverityInfo,
+				Code:     CodeJunkLine,
+				Line:     l.LineNo,
+				Message:  fmt.Sprintf("skipped %q: no tree marker and does not look like a file or path", name),
+			})
 			continue
 		}
 
@@ -205,10 +383,12 @@ func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]No
 			minStackSize = 1
 		}
 
+		lastPopped := -1
 		for len(stack) > minStackSize {
 			topIndent := indentStack[len(indentStack)-1]
 			if topIndent >= indent {
 				// Current line is same level or shallower -> Pop to find sibling/parent
+				lastPopped = topIndent
 				stack = stack[:len(stack)-1]
 				indentStack = indentStack[:len(indentStack)-1]
 			} else {
@@ -217,6 +397,21 @@ func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]No
 			}
 		}
 
+		// We popped a level deeper than ourselves and stopped at one shallower:
+		// the line sits between two known levels, so the parent is a guess.
+		if lastPopped > indent {
+			parent := "."
+			if len(stack) > 0 {
+				parent = path.Join(stack...)
+			}
+			diags = append(diags, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     CodeAmbiguousIndent,
+				Line:     l.LineNo,
+				Message:  fmt.Sprintf("indentation of %q matches no earlier level; attached to %q", name, parent),
+			})
+		}
+
 		// If rootIdx==0 and we popped everything down to root,
 		// we verify current indent > root indent.
 		// If not, it technically shouldn't be a child, but standard behavior is to just add it to root?
@@ -229,7 +424,9 @@ func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]No
 
 		// Determine Kind
 		kind := File
-		if isDirLike(name) {
+		if l.Target != "" {
+			kind = Symlink
+		} else if l.IsDir || isDirLike(name) {
 			kind = Dir
 			name = strings.TrimSuffix(name, "/")
 		} else {
@@ -239,7 +436,7 @@ func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]No
 		}
 
 		fullPath := path.Join(stack...)
-		nodes = append(nodes, Node{Path: fullPath, Kind: kind})
+		nodes = append(nodes, Node{Path: fullPath, Kind: kind, Target: l.Target, Mode: l.Mode, Line: l.LineNo, Column: l.Column, Description: l.Description})
 	}
 
 	// Post-pass: Fix "File" that became a parent
@@ -264,11 +461,35 @@ func parseTree(lines []LineInfo, warnings []string, indentedListMode bool) ([]No
 		// If "A/B" exists, then "A" must be a Dir
 		parent := path.Dir(n.Path)
 		if parent != "." && parent != "/" {
-			if idx, ok := pathToKind[parent]; ok {
+			if idx, ok := pathToKind[parent]; ok && nodes[idx].Kind != Dir {
+				diags = append(diags, promotedDiagnostic(nodes[idx]))
 				nodes[idx].Kind = Dir
+				nodes[idx].Target = ""
 			}
 		}
 	}
 
-	return nodes, warnings
+	return nodes, diags
+}
+
+// promotedDiagnostic reports a file (or symlink) that the post-pass is about
+// to turn into a directory. Bare names like "src" are routine in indented
+// lists; names that look like files ("main.py") or links having children
+// usually mean the indentation is off.
+func promotedDiagnostic(n Node) Diagnostic {
+	sev := SeverityInfo
+	msg := fmt.Sprintf("%q has children, treating it as a directory", n.Path)
+	switch {
+	case n.Kind == Symlink:
+		sev = SeverityWarning
+		msg = fmt.Sprintf("symlink %q has children, treating it as a directory", n.Path)
+	case looksLikeFile(path.Base(n.Path)):
+		sev = SeverityWarning
+	}
+	return Diagnostic{
+		Severity: sev,
+		Code:     CodePromotedDir,
+		Line:     n.Line,
+		Message:  msg,
+	}
 }
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -33,8 +34,8 @@ func TestParse_Golden(t *testing.T) {
 			if len(res.Nodes) == 0 {
 				t.Errorf("Expected nodes, got none")
 			}
-			if len(res.Warnings) > 0 {
-				t.Logf("Warnings: %v", res.Warnings)
+			if len(res.Diagnostics) > 0 {
+				t.Logf("Diagnostics: %v", res.Diagnostics)
 			}
 
 			// Basic sanity check: ensure main.py exists
@@ -52,6 +53,33 @@ func TestParse_Golden(t *testing.T) {
 	}
 }
 
+func TestParse_ASCIILastChildMarker(t *testing.T) {
+	// "`--" starts with a backtick but is a marker, not a quoted name.
+	files := []string{
+		"messy_ascii.txt", "correct_windows.tree", "distorted1.txt", "distorted2.tree",
+		"root_issue.txt", "test2-acii.tree", "torture.txt", "grand_finale.tree",
+	}
+	for _, name := range files {
+		t.Run(name, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("../../testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := Parse(string(content))
+			for _, n := range res.Nodes {
+				if strings.Contains(n.Path, "`--") {
+					t.Errorf("marker kept in path %q", n.Path)
+				}
+			}
+		})
+	}
+
+	res := Parse("app/\n|-- src/\n|   `-- main.py\n`-- README.md\n")
+	if res.Normalized != "app/\napp/src/\napp/src/main.py\napp/README.md" {
+		t.Errorf("unexpected normalized output:\n%s", res.Normalized)
+	}
+}
+
 func TestParse_EdgeCases(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -118,3 +146,690 @@ func TestParse_AllTestData(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_LineNumbersAndDiagnostics(t *testing.T) {
+	input := `project/
+├── src/
+
+│   └── main.py
+│       └── helper.py
+├── src/
+└── README.md
+TODO: fix this later`
+
+	res := Parse(input)
+
+	byPath := make(map[string]Node)
+	for _, n := range res.Nodes {
+		byPath[n.Path] = n
+	}
+
+	main, ok := byPath["project/src/main.py"]
+	if !ok {
+		t.Fatalf("main.py missing. Nodes: %v", res.Nodes)
+	}
+	if main.Line != 4 || main.Column != 9 {
+		t.Errorf("main.py: expected line 4 col 9, got line %d col %d", main.Line, main.Column)
+	}
+	if main.Kind != Dir {
+		t.Errorf("main.py has a child and should be promoted to a dir")
+	}
+
+	codes := make(map[string]int)
+	for _, d := range res.Diagnostics {
+		codes[d.Code] = d.Line
+	}
+	expected := map[string]int{
+		CodePromotedDir:   4,
+		CodeDuplicatePath: 6,
+		CodeJunkLine:      8,
+	}
+	for code, line := range expected {
+		got, ok := codes[code]
+		if !ok {
+			t.Errorf("expected a %s diagnostic, got %v", code, res.Diagnostics)
+			continue
+		}
+		if got != line {
+			t.Errorf("%s: expected line %d, got %d", code, line, got)
+		}
+	}
+}
+
+func TestParse_AmbiguousIndent(t *testing.T) {
+	input := `root
+    a
+        b
+      c`
+
+	res := Parse(input)
+
+	found := false
+	for _, d := range res.Diagnostics {
+		if d.Code == CodeAmbiguousIndent && d.Line == 4 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected ambiguous-indent on line 4, got %v", res.Diagnostics)
+	}
+}
+
+func TestParse_MarkdownBlocks(t *testing.T) {
+	input := "Here is your project:\n\n" +
+		"```bash\nnpm install\n```\n\n" +
+		"```text\napp/\n├── src/\n│   └── index.ts\n└── package.json\n```\n\n" +
+		"```ts\nconsole.log(\"hi\");\n```\n"
+
+	res := Parse(input)
+	if res.Normalized != "app/\napp/src/\napp/src/index.ts\napp/package.json" {
+		t.Errorf("auto block selection picked the wrong block. Got:\n%s", res.Normalized)
+	}
+	for _, n := range res.Nodes {
+		if n.Path == "app/src/index.ts" && n.Line != 10 {
+			t.Errorf("line numbers should refer to the original input, got %d", n.Line)
+		}
+	}
+
+	res, err := ParseWithOptions(input, Options{Block: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Nodes) != 1 || res.Nodes[0].Path != "npm install" {
+		t.Errorf("--block 1 should parse only the first block, got %v", res.Nodes)
+	}
+
+	if _, err := ParseWithOptions(input, Options{Block: 4}); err == nil {
+		t.Error("expected an error for an out-of-range block")
+	}
+}
+
+func TestParse_InlineContents(t *testing.T) {
+	input := "```\nshop/\n├── main.go\n└── internal/\n    └── cart.go\n```\n\n" +
+		"### `main.go`\n\n```go\npackage main\n```\n\n" +
+		"**internal/cart.go**\n```go title=\"internal/cart.go\"\npackage internal\n```\n\n" +
+		"### other.py\n```python\npass\n```\n"
+
+	res := Parse(input)
+
+	contents := make(map[string]string)
+	for _, n := range res.Nodes {
+		contents[n.Path] = n.Content
+	}
+	if contents["shop/main.go"] != "package main\n" {
+		t.Errorf("main.go content not attached, got %q", contents["shop/main.go"])
+	}
+	if contents["shop/internal/cart.go"] != "package internal\n" {
+		t.Errorf("cart.go content not attached, got %q", contents["shop/internal/cart.go"])
+	}
+
+	unmatched := 0
+	for _, d := range res.Diagnostics {
+		if d.Code == CodeUnmatchedContent {
+			unmatched++
+		}
+	}
+	if unmatched != 1 {
+		t.Errorf("expected 1 unmatched-content diagnostic for other.py, got %v", res.Diagnostics)
+	}
+}
+
+func TestParse_InlineComments(t *testing.T) {
+	inputs := map[string]string{
+		"tree":  "app/\n├── cmd/        # entry points\n│   └── main.go // wires it up\n└── go.mod  <-- module file\n",
+		"list":  "app/\n  cmd/  # entry points\n    main.go   // wires it up\n  go.mod ← module file\n",
+		"paths": "app/cmd/  # entry points\napp/cmd/main.go // wires it up\napp/go.mod <-- module file\n",
+		"yaml":  "app:\n  cmd:  # entry points\n    main.go:  # wires it up\n  go.mod:\n    description: module file\n",
+	}
+	want := map[string]string{"app/cmd": "entry points", "app/cmd/main.go": "wires it up", "app/go.mod": "module file"}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			res := Parse(input)
+			got := make(map[string]string)
+			for _, n := range res.Nodes {
+				got[n.Path] = n.Description
+			}
+			for path, desc := range want {
+				if d, ok := got[path]; !ok || d != desc {
+					t.Errorf("%s: description %q, want %q (nodes %v)", path, d, desc, res.Nodes)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"handlers/{user,order}.go", []string{"handlers/user.go", "handlers/order.go"}},
+		{"m/00{1..3}_init.sql", []string{"m/001_init.sql", "m/002_init.sql", "m/003_init.sql"}},
+		{"{08..10}", []string{"08", "09", "10"}},
+		{"{5..1..2}", []string{"5", "3", "1"}},
+		{"{c..a}", []string{"c", "b", "a"}},
+		{"x{a,b{1,2}}y", []string{"xay", "xb1y", "xb2y"}},
+		{"{a,b}{1,2}", []string{"a1", "a2", "b1", "b2"}},
+		{"{{name}}", []string{"{{name}}"}},
+		{"${HOME}", []string{"${HOME}"}},
+		{"{a}{b,c}", []string{"{a}b", "{a}c"}},
+		{`\{a,b\}`, []string{"{a,b}"}},
+		{`{a\,b,c}`, []string{"a,b", "c"}},
+		{"{1..a}", []string{"{1..a}"}},
+	}
+	for _, tt := range tests {
+		got, ok := expandBraces(tt.in)
+		if !ok || strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("expandBraces(%q) = %q, %v; want %q", tt.in, got, ok, tt.want)
+		}
+	}
+
+	if got, ok := expandBraces("f{1..100000}"); ok || len(got) != 1 {
+		t.Errorf("oversized range should be kept as written, got %d paths", len(got))
+	}
+}
+
+func TestParse_BraceExpansion(t *testing.T) {
+	inputs := map[string]string{
+		"tree":  "app/\n├── {api,web}/\n│   └── main.go\n└── db/\n    └── 00{1..2}.sql  # migrations\n",
+		"paths": "app/{api,web}/main.go\napp/db/00{1..2}.sql\n",
+	}
+	want := []string{"app/api/main.go", "app/web/main.go", "app/db/001.sql", "app/db/002.sql"}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			res := Parse(input)
+			paths := make(map[string]Node)
+			for _, n := range res.Nodes {
+				paths[n.Path] = n
+			}
+			for _, p := range want {
+				if _, ok := paths[p]; !ok {
+					t.Errorf("missing %s in:\n%s", p, res.Normalized)
+				}
+			}
+			if strings.Contains(res.Normalized, "{") {
+				t.Errorf("unexpanded pattern left in:\n%s", res.Normalized)
+			}
+			if n := paths["app/db/002.sql"]; name == "tree" && (n.Line != 5 || n.Description != "migrations") {
+				t.Errorf("expanded node should keep line and description, got %+v", n)
+			}
+		})
+	}
+
+	res := Parse("src/\n  big{1..100000}.txt\n")
+	warned := false
+	for _, d := range res.Diagnostics {
+		warned = warned || d.Code == CodeBraceExpansion
+	}
+	if len(res.Nodes) != 2 || !warned {
+		t.Errorf("oversized pattern should stay literal with a warning, got %v %v", res.Nodes, res.Diagnostics)
+	}
+}
+
+func TestParse_QuotedNames(t *testing.T) {
+	inputs := map[string]string{
+		"tree":  "\"My Project/\"\n├── \"My Docs/\"  # docs\n│   └── 'a # b.txt'\n├── C\\#\\ notes.md\n├── \"a | b.txt\"\n└── `{x,y}.go`\n",
+		"list":  "\"My Project/\"\n  \"My Docs/\"  # docs\n    'a # b.txt'\n  C\\#\\ notes.md\n  \"a | b.txt\"\n  `{x,y}.go`\n",
+		"paths": "\"My Project/My Docs/\"  # docs\n\"My Project\"/My\\ Docs/'a # b.txt'\n\"My Project/C# notes.md\"\n\"My Project/a | b.txt\"\n\"My Project/{x,y}.go\"\n",
+	}
+	want := []string{
+		"My Project/My Docs/",
+		"My Project/My Docs/a # b.txt",
+		"My Project/C# notes.md",
+		"My Project/a | b.txt",
+		"My Project/{x,y}.go",
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			res := Parse(input)
+			for _, p := range want {
+				if !strings.Contains("\n"+res.Normalized+"\n", "\n"+p+"\n") {
+					t.Errorf("missing %q in:\n%s", p, res.Normalized)
+				}
+			}
+			for _, n := range res.Nodes {
+				if n.Path == "My Project/My Docs" && n.Description != "docs" {
+					t.Errorf("comment after a quoted name should be kept, got %q", n.Description)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Symlinks(t *testing.T) {
+	inputs := map[string]string{
+		"tree":      "app/\n├── shared/\n│   └── config.yml\n├── config.yml -> shared/config.yml  # one copy\n└── \"My Link\" -> \"My Docs\"\n",
+		"paths":     "app/shared/config.yml\napp/config.yml -> shared/config.yml  # one copy\n\"app/My Link\" -> \"My Docs\"\n",
+		"tree-json": `[{"type":"directory","name":"app","contents":[{"type":"directory","name":"shared","contents":[{"type":"file","name":"config.yml"}]},{"type":"link","name":"config.yml","target":"shared/config.yml"},{"type":"link","name":"My Link","target":"My Docs"}]}]`,
+		"yaml":      "app:\n  shared:\n    config.yml:\n  config.yml:\n    target: shared/config.yml\n  My Link:\n    target: My Docs\n",
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			res := Parse(input)
+			links := map[string]string{}
+			for _, n := range res.Nodes {
+				if n.Kind == Symlink {
+					links[n.Path] = n.Target
+				}
+			}
+			if links["app/config.yml"] != "shared/config.yml" || links["app/My Link"] != "My Docs" || len(links) != 2 {
+				t.Errorf("links = %v\n%s", links, res.Normalized)
+			}
+			if !strings.Contains(res.Normalized, "app/config.yml -> shared/config.yml\n") {
+				t.Errorf("Normalized should show the target:\n%s", res.Normalized)
+			}
+		})
+	}
+
+	// An arrow without spaces is part of the name, and a link with children
+	// is a directory after all.
+	res := Parse("a->b.txt\nlib -> vendor\n  x.go\n")
+	for _, n := range res.Nodes {
+		if n.Kind == Symlink {
+			t.Errorf("%s should not be a symlink", n.Path)
+		}
+	}
+	if !strings.Contains(res.Normalized, "a->b.txt\n") || !strings.Contains(res.Normalized, "lib/\n") {
+		t.Errorf("Normalized:\n%s", res.Normalized)
+	}
+}
+
+func TestParse_ModeAnnotations(t *testing.T) {
+	inputs := map[string]string{
+		"tree":    "app/\n├── bin/ [700]\n│   └── deploy.sh [755]  # ships it\n├── -rw------- key.pem\n└── \"odd [755]\"\n",
+		"tree -p": "app\n├── [drwx------]  bin\n│   └── [-rwxr-xr-x  1.2K]  deploy.sh\n├── [-rw-------]  key.pem\n└── \"odd [755]\"\n",
+		"paths":   "app/bin/ [700]\napp/bin/deploy.sh [rwxr-xr-x]  # ships it\n-rw------- app/key.pem\n\"app/odd [755]\"\n",
+	}
+	want := map[string]fs.FileMode{"app/bin": 0700, "app/bin/deploy.sh": 0755, "app/key.pem": 0600, "app/odd [755]": 0}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			res := Parse(input)
+			got := map[string]fs.FileMode{}
+			for _, n := range res.Nodes {
+				if n.Path != "app" {
+					got[n.Path] = n.Mode
+				}
+				if n.Path == "app/bin" && n.Kind != Dir {
+					t.Errorf("app/bin should be a directory")
+				}
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+			for p, mode := range want {
+				if m, ok := got[p]; !ok || m != mode {
+					t.Errorf("%s: mode %o (found %v), want %o", p, m, ok, mode)
+				}
+			}
+		})
+	}
+}
+
+func TestQuoteName(t *testing.T) {
+	for _, name := range []string{"main.go", "My Docs/", "a # b.txt", "a | b.txt", "{a,b}.txt", "run.sh [755]", "-rwxr-xr-x x", `back\slash`, `"q".txt`, "it's.md", "- x", " pad "} {
+		quoted := QuoteName(name)
+		lexed, comment, _ := splitName(quoted)
+		if got := unescapeName(lexed); got != name || comment != "" {
+			t.Errorf("QuoteName(%q) = %s, reads back as %q", name, quoted, got)
+		}
+	}
+	if got := QuoteName("run.sh [755]"); got != `"run.sh [755]"` {
+		t.Errorf("a name that looks like a mode annotation needs quotes, got %s", got)
+	}
+	if got := QuoteName("My Docs/"); got != "My Docs/" {
+		t.Errorf("spaces alone need no quotes in a tree, got %s", got)
+	}
+	if got := QuotePath("My Docs/a.txt"); got != `"My Docs/a.txt"` {
+		t.Errorf("spaces need quotes in a path list, got %s", got)
+	}
+}
+
+func TestCaptionPath(t *testing.T) {
+	tests := map[string]string{
+		"### src/main.go":               "src/main.go",
+		"**`app.py`**:":                 "app.py",
+		"Create `src/util.go` with:":    "src/util.go",
+		"1. File: config/settings.yaml": "config/settings.yaml",
+		"#### main.go (entry point)":    "main.go",
+		"And the main file:":            "",
+		"### Installation":              "",
+	}
+	for line, want := range tests {
+		if got := captionPath(line); got != want {
+			t.Errorf("captionPath(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestParse_AbsoluteAndEscapingPaths(t *testing.T) {
+	res := Parse("/etc/nginx/\n/var/log/app.log\n../../.ssh/authorized_keys\n")
+
+	if res.Normalized != "etc/nginx/\nvar/log/app.log\n../../.ssh/authorized_keys" {
+		t.Errorf("unexpected normalized output:\n%s", res.Normalized)
+	}
+
+	codes := make(map[string]int)
+	for _, d := range res.Diagnostics {
+		codes[d.Code]++
+	}
+	if codes[CodeAbsolutePath] != 2 || codes[CodeOutsideRoot] != 1 {
+		t.Errorf("expected 2 absolute-path and 1 outside-root diagnostics, got %v", res.Diagnostics)
+	}
+}
+
+func TestParse_TreeJSON(t *testing.T) {
+	input := `[
+  {"type":"directory","name":".","contents":[
+    {"type":"directory","name":"cmd","mode":"0755","prot":"drwxr-xr-x","contents":[
+      {"type":"file","name":"main.go","mode":"0644","size":120}
+    ]},
+    {"type":"file","name":"run.sh","prot":"-rwxr-xr-x","size":30},
+    {"type":"file","name":"notes (draft).txt"},
+    {"type":"directory","name":"empty","contents":[]}
+  ]}
+,
+  {"type":"report","directories":2,"files":3}
+]`
+	res := Parse(input)
+
+	if res.Format != "tree-json" {
+		t.Fatalf("expected tree-json format, got %q (%v)", res.Format, res.Diagnostics)
+	}
+	want := "cmd/\ncmd/main.go\nrun.sh\nnotes (draft).txt\nempty/"
+	if res.Normalized != want {
+		t.Errorf("unexpected normalized output:\n%s", res.Normalized)
+	}
+
+	byPath := make(map[string]Node)
+	for _, n := range res.Nodes {
+		byPath[n.Path] = n
+	}
+	if n := byPath["cmd/main.go"]; n.Size != 120 || n.Mode != 0644 {
+		t.Errorf("cmd/main.go: size %d mode %o, want 120 644", n.Size, n.Mode)
+	}
+	if n := byPath["run.sh"]; n.Mode != 0755 {
+		t.Errorf("run.sh: mode %o from prot, want 755", n.Mode)
+	}
+	if n := byPath["cmd"]; n.Kind != Dir || n.Mode != 0755 {
+		t.Errorf("cmd: kind %s mode %o, want dir 755", n.Kind, n.Mode)
+	}
+}
+
+func TestParse_TreeXML(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<tree>
+  <directory name="proj">
+    <directory name="src">
+      <file name="a &amp; b.txt" size="3" mode="0600"></file>
+    </directory>
+    <file name="README.md"></file>
+  </directory>
+  <report>
+    <directories>2</directories>
+    <files>2</files>
+  </report>
+</tree>`
+	res := Parse(input)
+
+	if res.Format != "tree-xml" {
+		t.Fatalf("expected tree-xml format, got %q (%v)", res.Format, res.Diagnostics)
+	}
+	want := "proj/\nproj/src/\nproj/src/a & b.txt\nproj/README.md"
+	if res.Normalized != want {
+		t.Errorf("unexpected normalized output:\n%s", res.Normalized)
+	}
+	if n := res.Nodes[2]; n.Size != 3 || n.Mode != 0600 {
+		t.Errorf("%s: size %d mode %o, want 3 600", n.Path, n.Size, n.Mode)
+	}
+}
+
+func TestParse_BrokenTreeJSONFallsBack(t *testing.T) {
+	res := Parse(`[{"type":"directory","name":"src", ` + "\nmain.go\n")
+	if res.Format != FormatText {
+		t.Errorf("expected fallback to text parsing, got %q", res.Format)
+	}
+	if len(res.Diagnostics) == 0 || res.Diagnostics[0].Code != CodeStructuredInput {
+		t.Errorf("expected a structured-input warning, got %v", res.Diagnostics)
+	}
+}
+
+func TestParse_ToolListings(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format string
+		want   string
+	}{
+		{
+			name:   "ls -R",
+			input:  ".:\ncmd  empty  go.mod\n\n./cmd:\nmain.go\n\n./empty:\n",
+			format: "ls-r",
+			want:   "cmd/\ncmd/main.go\nempty/\ngo.mod",
+		},
+		{
+			name:   "ls -RF of a subdirectory",
+			input:  "src:\napi/\nrun.sh*\n\nsrc/api:\nh.go\n",
+			format: "ls-r",
+			want:   "src/\nsrc/api/\nsrc/api/h.go\nsrc/run.sh",
+		},
+		{
+			name:   "find",
+			input:  ".\n./cmd\n./cmd/main.go\n./go.mod\n",
+			format: "find",
+			want:   "cmd/\ncmd/main.go\ngo.mod",
+		},
+		{
+			name:   "find -printf '%y %p'",
+			input:  "d .\nd ./cmd\nf ./cmd/main.go\nd ./empty\nf ./go.mod\n",
+			format: "find",
+			want:   "cmd/\ncmd/main.go\nempty/\ngo.mod",
+		},
+		{
+			name:   "git ls-files",
+			input:  "Makefile\ncmd/main.go\n\"docs/caf\\303\\251.md\"\ninternal/a/b.go\n",
+			format: "git-ls-files",
+			want:   "Makefile\ncmd/\ncmd/main.go\ndocs/\ndocs/café.md\ninternal/\ninternal/a/\ninternal/a/b.go",
+		},
+		{
+			name: "tar -tv (GNU)",
+			input: "drwxr-xr-x me/me         0 2024-01-02 15:04 ./\n" +
+				"-rwxr-xr-x me/me        10 2024-01-02 15:04 ./bin/run.sh\n" +
+				"lrwxrwxrwx me/me         0 2024-01-02 15:04 ./bin/latest -> run.sh\n" +
+				"-rw-r--r-- me/me         3 2024-01-02 15:04 ./my notes.txt\n",
+			format: "tar-tv",
+			want:   "bin/\nbin/run.sh\nbin/latest -> run.sh\nmy notes.txt",
+		},
+		{
+			name:   "tar -tv (bsdtar)",
+			input:  "drwxr-xr-x  0 me     staff       0 Jan  2 15:04 src/\n-rw-r--r--  0 me     staff      12 Jan  2  2023 src/a.go\n",
+			format: "tar-tv",
+			want:   "src/\nsrc/a.go",
+		},
+		{
+			name: "ls -l",
+			input: "total 8\ndrwxr-xr-x  2 me  staff  4096 Oct 16 21:18 shared\n" +
+				"lrwxrwxrwx  1 me  staff    17 Oct 16 21:18 config.yml -> shared/config.yml\n",
+			format: "tar-tv",
+			want:   "shared/\nconfig.yml -> shared/config.yml",
+		},
+		{
+			name: "unzip -l",
+			input: "Archive:  site.zip\n  Length      Date    Time    Name\n---------  ---------- -----   ----\n" +
+				"        0  2024-01-02 15:04   site/\n      120  2024-01-02 15:04   site/css/main.css\n" +
+				"---------                     -------\n      120                     2 files\n",
+			format: "unzip-l",
+			want:   "site/\nsite/css/\nsite/css/main.css",
+		},
+		{
+			name:   "git ls-files with a tracked .gitignore",
+			input:  ".gitignore\ncmd/main.go\n",
+			format: "git-ls-files",
+			want:   ".gitignore\ncmd/\ncmd/main.go",
+		},
+		{
+			name:   "sorted path list with braces",
+			input:  "handlers/{user,order}.go\nmigrations/00{1..3}.sql\n",
+			format: FormatText,
+			want:   "handlers/user.go\nhandlers/order.go\nmigrations/001.sql\nmigrations/002.sql\nmigrations/003.sql",
+		},
+		{
+			name:   "sorted path list with a mode",
+			input:  "README.md\nscripts/deploy.sh [755]\n",
+			format: FormatText,
+			want:   "README.md\nscripts/deploy.sh",
+		},
+		{
+			name:   "sorted path list with a symlink",
+			input:  "config/app -> ../shared\nshared/config.yml\n",
+			format: FormatText,
+			want:   "config/app -> ../shared\nshared/config.yml",
+		},
+		{
+			name:   "unsorted path list stays a path list",
+			input:  "src/main.go\nsrc/util.go\nREADME.md\n",
+			format: FormatText,
+			want:   "src/main.go\nsrc/util.go\nREADME.md",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Parse(tt.input)
+			if res.Format != tt.format {
+				t.Errorf("format = %q, want %q", res.Format, tt.format)
+			}
+			if res.Normalized != tt.want {
+				t.Errorf("unexpected normalized output:\n%s\nwant:\n%s", res.Normalized, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_ProseHeaderIsNotLsR(t *testing.T) {
+	inputs := []string{
+		"Here is the layout:\nmyapp/\n├── src/\n│   └── main.go\n└── README.md",
+		"Project structure:\nsrc/\nREADME.md\n",
+	}
+	for _, in := range inputs {
+		res := Parse(in)
+		if res.Format != FormatText {
+			t.Errorf("%q: format = %q, want %q", in, res.Format, FormatText)
+		}
+		for _, n := range res.Nodes {
+			if hasTreeArt(n.Path) {
+				t.Errorf("%q: tree art leaked into path %q", in, n.Path)
+			}
+		}
+	}
+}
+
+func TestParse_StructuredSpecs(t *testing.T) {
+	jsonSpec := `{
+  "src": {"main.go": null, "pkg": {}, "handlers": ["user.go", "order.go"]},
+  "README.md": "# Title\n",
+  "bin/": {"run": {"mode": "0755", "template": "run.sh"}}
+}`
+	yamlSpec := `# same layout as YAML
+src:
+  main.go:          # empty file
+  pkg: {}
+  handlers: [user.go, order.go]
+README.md: |
+  # Title
+bin/:
+  - run:
+      mode: "0755"
+      template: run.sh
+`
+	want := "src/\nsrc/main.go\nsrc/pkg/\nsrc/handlers/\nsrc/handlers/user.go\nsrc/handlers/order.go\nREADME.md\nbin/\nbin/run"
+
+	for _, tt := range []struct {
+		format string
+		input  string
+		opts   Options
+	}{
+		{"json", jsonSpec, Options{}},
+		{"yaml", yamlSpec, Options{}},
+		{"yaml", yamlSpec, Options{Format: "yaml"}},
+	} {
+		res, err := ParseWithOptions(tt.input, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if res.Format != tt.format {
+			t.Errorf("%s: detected format %q (%v)", tt.format, res.Format, res.Diagnostics)
+		}
+		if res.Normalized != want {
+			t.Errorf("%s: unexpected normalized output:\n%s", tt.format, res.Normalized)
+		}
+
+		byPath := make(map[string]Node)
+		for _, n := range res.Nodes {
+			byPath[n.Path] = n
+		}
+		if n := byPath["README.md"]; n.Content != "# Title\n" {
+			t.Errorf("%s: README.md content %q", tt.format, n.Content)
+		}
+		if n := byPath["bin/run"]; n.Mode != 0755 || n.Template != "run.sh" || n.Kind != File {
+			t.Errorf("%s: bin/run = %+v", tt.format, n)
+		}
+		if n := byPath["src/main.go"]; n.Line != 2 && n.Line != 3 {
+			t.Errorf("%s: src/main.go on line %d", tt.format, n.Line)
+		}
+	}
+}
+
+func TestParse_StructuredSpecErrors(t *testing.T) {
+	if _, err := ParseWithOptions("src:\n  main.go: [\n", Options{Format: "yaml"}); err == nil {
+		t.Error("expected an error for malformed YAML with an explicit format")
+	}
+	if _, err := ParseWithOptions("src/\n", Options{Format: "toml"}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	// Text trees with a colon in them must not be mistaken for YAML.
+	res := Parse("Project layout:\nsrc/\n  main.go\n")
+	if res.Format != FormatText {
+		t.Errorf("text tree detected as %q", res.Format)
+	}
+}
+
+func TestParse_IndentedListSiblings(t *testing.T) {
+	tests := map[string]string{
+		"src/\n  main.go\ndocs/\n":        "src/\nsrc/main.go\ndocs/",
+		"a/\nb/\nc.txt\n":                 "a/\nb/\nc.txt",
+		"- src/\n  - `__init__.py`\n- x/": "src/\nsrc/__init__.py\nx/",
+		"proj\n  src\n    a.go\n  b.md\n": "proj/\nproj/src/\nproj/src/a.go\nproj/b.md",
+	}
+	for input, want := range tests {
+		if got := Parse(input).Normalized; got != want {
+			t.Errorf("Parse(%q):\n%s\nwant:\n%s", input, got, want)
+		}
+	}
+}
+
+func TestParse_HTMLList(t *testing.T) {
+	input := `<ul>
+  <li>src/
+    <ul>
+      <li>a &amp; b.txt</li>
+      <li>pkg<ul><li>x.go</li></ul></li>
+    </ul>
+  </li>
+  <li>README.md</li>
+</ul>`
+	res := Parse(input)
+	if res.Format != "html" {
+		t.Fatalf("format = %q", res.Format)
+	}
+	want := "src/\nsrc/a & b.txt\nsrc/pkg/\nsrc/pkg/x.go\nREADME.md"
+	if res.Normalized != want {
+		t.Errorf("unexpected normalized output:\n%s", res.Normalized)
+	}
+	if res.Nodes[4].Line != 8 {
+		t.Errorf("README.md on line %d, want 8", res.Nodes[4].Line)
+	}
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// commentMarkers start an inline comment when they follow unquoted whitespace.
+var commentMarkers = []string{"#", "//", "<--", "←"}
+
+// escapable are the characters a backslash makes literal outside quotes. Any
+// other backslash is a Windows path separator.
+const escapable = " \t#\"'`\\{},<"
+
+// splitName reads the name at the start of a spec line and returns it, its
+// trailing comment without the marker, and whether any part of it was
+// quoted or escaped.
+//
+// A name, or one of its path elements, may be wrapped in "double quotes"
+// (where \" and \\ are escapes), 'single quotes' or `backticks`, which keep
+// spaces, comment markers and braces literal. Outside quotes a backslash
+// escapes one of the characters in escapable. Literal braces, commas and
+// backslashes come back escaped for expandBraces; see unescapeName.
+func splitName(s string) (name, comment string, quoted bool) {
+	var b strings.Builder
+	literal := func(c byte) {
+		if strings.IndexByte(`{},\`, c) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case (c == '"' || c == '\'' || c == '`') && (b.Len() == 0 || strings.HasSuffix(b.String(), "/")):
+			end := closingQuote(s, i)
+			if end < 0 {
+				break // a lone quote is just a character
+			}
+			for j := i + 1; j < end; j++ {
+				if c == '"' && s[j] == '\\' && (s[j+1] == '"' || s[j+1] == '\\') {
+					j++
+				}
+				literal(s[j])
+			}
+			i, quoted = end+1, true
+			continue
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) >= 0:
+			literal(s[i+1])
+			i, quoted = i+2, true
+			continue
+		case c == '\\':
+			b.WriteByte('/')
+			i++
+			continue
+		case c == ' ' || c == '\t':
+			rest := strings.TrimLeft(s[i:], " \t")
+			for _, m := range commentMarkers {
+				if strings.HasPrefix(rest, m) {
+					// Repeats of the marker ("## ...", "<---") belong to it.
+					_, size := utf8.DecodeLastRuneInString(m)
+					text := strings.TrimLeft(rest[len(m):], m[len(m)-size:])
+					return b.String(), strings.TrimSpace(text), quoted
+				}
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String(), "", quoted
+}
+
+// splitEntry is splitName for a line that may be a symlink, "name ->
+// target": the arrow needs spaces around it and must come before any
+// comment. The target is returned unescaped, and the comment may follow
+// either part.
+func splitEntry(s string) (name, target, comment string, quoted bool) {
+	arrow := -1
+scan:
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			if end := closingQuote(s, i); end > 0 {
+				i = end
+			}
+		case c == '\\':
+			i++
+		case c == ' ' || c == '\t':
+			rest := strings.TrimLeft(s[i:], " \t")
+			if strings.HasPrefix(rest, "-> ") || rest == "->" {
+				arrow = i
+				break scan
+			}
+			for _, m := range commentMarkers {
+				if strings.HasPrefix(rest, m) {
+					break scan
+				}
+			}
+		}
+	}
+	if arrow < 0 {
+		name, comment, quoted = splitName(s)
+		return name, "", comment, quoted
+	}
+
+	name, _, quoted = splitName(strings.TrimSpace(s[:arrow]))
+	rest := strings.TrimPrefix(strings.TrimLeft(s[arrow:], " \t"), "->")
+	target, comment, _ = splitName(strings.TrimSpace(rest))
+	return name, unescapeName(target), comment, quoted
+}
+
+// closingQuote returns the index of the quote closing the one at s[start],
+// or -1.
+func closingQuote(s string, start int) int {
+	q := s[start]
+	for j := start + 1; j < len(s); j++ {
+		switch {
+		case q == '"' && s[j] == '\\' && j+1 < len(s):
+			j++
+		case s[j] == q:
+			return j
+		}
+	}
+	return -1
+}
+
+// QuoteName returns name as it must be written in a tree line to read back
+// unchanged: as is if it can, otherwise in double quotes. Spaces alone do not
+// need quotes in a tree; comment markers, tree markers, " -> ", quotes,
+// backslashes, surrounding spaces, list bullets, mode annotations and braces
+// that would expand do.
+func QuoteName(name string) string {
+	if readsBack(name) {
+		return name
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
+// QuotePath is QuoteName for a line of a path list, where a space or a
+// leading comment marker would also change how the line is read.
+func QuotePath(p string) string {
+	if strings.ContainsAny(p, " \t") || strings.HasPrefix(p, "#") || strings.HasPrefix(p, "//") {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(p) + `"`
+	}
+	return QuoteName(p)
+}
+
+// readsBack reports whether name survives the scanner and brace expansion.
+func readsBack(name string) bool {
+	if name == "" || strings.TrimSpace(name) != name || hasBranchMarker(name) ||
+		strings.HasPrefix(name, "- ") || strings.HasPrefix(name, "* ") {
+		return false
+	}
+	if _, mode, _ := cutMode(name); mode != 0 {
+		return false
+	}
+	lexed, target, comment, _ := splitEntry(name)
+	if comment != "" || target != "" {
+		return false
+	}
+	paths, _ := expandBraces(lexed)
+	return len(paths) == 1 && paths[0] == name
+}
+
+// nameQuote returns the index of the first quote in line that opens a name,
+// or -1. The backtick of the ASCII last-child marker "`--" is not one.
+func nameQuote(line string) int {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"', '\'':
+			return i
+		case '`':
+			if !strings.HasPrefix(line[i:], "`--") {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"io/fs"
 	"strings"
+	"unicode/utf8"
 )
 
 // LineInfo captures the properties of a single scanned line,
@@ -13,6 +15,24 @@ type LineInfo struct {
 	Marker     string // "├──", "└──", "|--", "+--", etc.
 	IsPathLike bool   // Contains slashes but no spaces?
 	IsComment  bool   // Starts with # or //
+	IsDir      bool   // Name carried a trailing slash before cleaning
+	LineNo     int    // 1-based line number in the original input
+	Column     int    // 1-based column (in runes) where CleanName starts
+
+	// Target is set for a symlink line, "name -> target".
+	Target string
+
+	// Mode holds the permission bits of a "deploy.sh [755]" or
+	// "-rwxr-xr-x deploy.sh" annotation; 0 if there is none.
+	Mode fs.FileMode
+
+	// Quoted is set when the name was quoted or had escapes, so spaces and
+	// special characters in it are meant.
+	Quoted bool
+
+	// Description is an inline annotation after the name ("main.go  # entry
+	// point", "db/ <-- migrations"), without its comment marker.
+	Description string
 }
 
 // ScanLines analyzes input text and returns structured info for each line.
@@ -21,7 +41,7 @@ func ScanLines(input string) []LineInfo {
 	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
 	result := make([]LineInfo, 0, len(lines))
 
-	for _, raw := range lines {
+	for i, raw := range lines {
 		trim := strings.TrimSpace(raw)
 		if trim == "" {
 			continue // Skip empty lines here? Or keep them for line number tracking? skipping for now
@@ -39,7 +59,7 @@ func ScanLines(input string) []LineInfo {
 			raw = raw[3:] // Hacky adjust
 		}
 
-		info := LineInfo{Raw: raw}
+		info := LineInfo{Raw: raw, LineNo: i + 1}
 
 		// 1. Check for comments
 		if strings.HasPrefix(trim, "#") || strings.HasPrefix(trim, "//") {
@@ -51,8 +71,11 @@ func ScanLines(input string) []LineInfo {
 		// 2. Normalize tabs for calculation
 		expanded := strings.ReplaceAll(raw, "\t", "    ")
 
-		// 3. Find Tree Marker
+		// 3. Find Tree Marker (before any quoted name, which may contain one)
 		idx, marker := findBranchMarker(expanded)
+		if q := nameQuote(expanded); q >= 0 {
+			idx, marker = findBranchMarker(expanded[:q])
+		}
 		if idx >= 0 {
 			info.Marker = marker
 			// Depth from marker: count pipes/spaces before it
@@ -69,34 +92,49 @@ func ScanLines(input string) []LineInfo {
 			info.CleanName = trim
 		}
 
-		// 4. Strip inline comments from name
-		// Supports: #, //, <--, (comment)
-		info.CleanName = stripInlineComment(info.CleanName)
-		info.CleanName = strings.TrimSpace(info.CleanName)
-		info.CleanName = strings.ReplaceAll(info.CleanName, "\\", "/") // Normalize Windows paths
-		info.CleanName = strings.TrimSuffix(info.CleanName, "/")       // Remove trailing slash for consistency (added back by Kind)
-
-		// Extra aggression: if it ends with " <-- ...", strip it
-		if idx := strings.Index(info.CleanName, " <--"); idx != -1 {
-			info.CleanName = info.CleanName[:idx]
-		}
-
-		// 5. Clean list bullets
+		// 4. Clean list bullets
 		info.CleanName = strings.TrimPrefix(info.CleanName, "- ")
 		info.CleanName = strings.TrimPrefix(info.CleanName, "* ")
 		info.CleanName = strings.TrimSpace(info.CleanName)
 
-		// 6. Path-like check
-		// Contains slash, no spaces (unless escaped, which we ignore for now)
-		if strings.Contains(info.CleanName, "/") && !strings.Contains(info.CleanName, " ") {
+		// 5. Take off a permission annotation: [755], [-rwxr-xr-x], drwxr-xr-x
+		var modeDir bool
+		info.CleanName, info.Mode, modeDir = cutMode(info.CleanName)
+
+		// 6. Unquote the name and split off a symlink target and inline comment
+		// Supports: "quoted", 'quoted', `code spans`, \ escapes; -> target; #, //, <--, ←
+		info.CleanName, info.Target, info.Description, info.Quoted = splitEntry(info.CleanName)
+		info.Target = linkTarget(info.Target)
+		info.IsDir = strings.HasSuffix(info.CleanName, "/") || modeDir
+		info.CleanName = strings.TrimSuffix(info.CleanName, "/") // Remove trailing slash for consistency (added back by Kind)
+
+		// 7. Path-like check
+		// Contains slash, no spaces (unless quoted or escaped)
+		if strings.Contains(info.CleanName, "/") && (info.Quoted || !strings.Contains(info.CleanName, " ")) {
 			info.IsPathLike = true
 		}
 
+		info.Column = nameColumn(lines[i], info.CleanName)
+
 		result = append(result, info)
 	}
 	return result
 }
 
+// nameColumn locates name inside the original line and returns its 1-based rune column.
+// Names that were rewritten during cleaning (e.g. backslashes) fall back to the first
+// non-blank character.
+func nameColumn(line, name string) int {
+	idx := -1
+	if name != "" {
+		idx = strings.Index(line, name)
+	}
+	if idx < 0 {
+		idx = len(line) - len(strings.TrimLeft(line, " \t"))
+	}
+	return utf8.RuneCountInString(line[:idx]) + 1
+}
+
 // Helper to count visual depth from tree graphics like "│   │   "
 func countGraphicDepth(prefix string) int {
 	// Simple heuristic: Count "│" or "|"
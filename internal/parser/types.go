@@ -1,20 +1,89 @@
 package parser
 
+import (
+	"fmt"
+	"io/fs"
+)
+
 type NodeKind string
 
 const (
-	Dir  NodeKind = "dir"
-	File NodeKind = "file"
+	Dir     NodeKind = "dir"
+	File    NodeKind = "file"
+	Symlink NodeKind = "symlink"
 )
 
 type Node struct {
-	Path string   // normalized relative path like "core/model/entity.py"
-	Kind NodeKind // dir or file
+	Path   string   // normalized relative path like "core/model/entity.py"
+	Kind   NodeKind // dir, file or symlink
+	Line   int      // 1-based input line the node came from (0 if unknown)
+	Column int      // 1-based column where the name starts on that line
+
+	// Content holds file contents supplied by the input itself (e.g. a code
+	// block under a "### src/main.go" heading). Empty means "not provided".
+	Content string
+
+	// Size and Mode are known when the input is a listing that records them
+	// (e.g. `tree -J -s -p`). Zero means "not provided".
+	Size int64
+	Mode fs.FileMode
+
+	// Template names the file whose --populate boilerplate this file gets
+	// (e.g. "Dockerfile" or ".py"), as set by a structured spec.
+	Template string
+
+	// Description is the annotation written next to the entry, such as
+	// "entry point" in "main.go  # entry point".
+	Description string
+
+	// Target is where a Symlink points, as written in the spec ("link ->
+	// target"): a slash path relative to the link's directory.
+	Target string
+}
+
+// Severity ranks how much a Diagnostic should worry the user.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Diagnostic codes emitted by the parser.
+const (
+	CodeJunkLine         = "junk-line"         // line dropped by the junk filter
+	CodeAmbiguousIndent  = "ambiguous-indent"  // indentation falls between two known levels
+	CodePromotedDir      = "promoted-dir"      // file turned into a directory because it has children
+	CodeDuplicatePath    = "duplicate-path"    // same path declared more than once
+	CodeAbsolutePath     = "absolute-path"     // absolute path rewritten as a relative one
+	CodeOutsideRoot      = "outside-root"      // path resolves outside the output directory
+	CodeMarkdownBlock    = "markdown-block"    // tree taken from a fenced code block
+	CodeInlineContent    = "inline-content"    // file contents taken from a captioned code block
+	CodeUnmatchedContent = "unmatched-content" // captioned code block matches no (or several) files
+	CodeStructuredInput  = "structured-input"  // input decoded as (or mistaken for) a tool's structured output
+	CodeBraceExpansion   = "brace-expansion"   // brace pattern too large to expand, kept as written
+)
+
+// Diagnostic describes something the parser noticed (or guessed) about the input.
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Line     int // 1-based input line (0 if not tied to a line)
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s [%s]", d.Line, d.Severity, d.Message, d.Code)
+	}
+	return fmt.Sprintf("%s: %s [%s]", d.Severity, d.Message, d.Code)
 }
 
 type Result struct {
 	Nodes        []Node
 	Normalized   string
-	Warnings     []string
-	RootInferred bool // Did we guess the root directory?
+	Diagnostics  []Diagnostic
+	RootInferred bool   // Did we guess the root directory?
+	Format       string // How the input was read: FormatText or a decoder such as "tree-json"
 }
@@ -58,21 +58,8 @@ func looksLikeFile(name string) bool {
 	return dot > 0 && dot < len(n)-1
 }
 
+// stripInlineComment returns s without a trailing comment (see splitName).
 func stripInlineComment(s string) string {
-	// Remove " # ..." and " // ..." when preceded by whitespace.
-	// This keeps URLs like "http://..." mostly intact because they usually
-	// won’t have a leading whitespace before the slashes in a filename.
-	if i := strings.Index(s, " #"); i >= 0 {
-		return s[:i]
-	}
-	if i := strings.Index(s, "\t#"); i >= 0 {
-		return s[:i]
-	}
-	if i := strings.Index(s, " //"); i >= 0 {
-		return s[:i]
-	}
-	if i := strings.Index(s, "\t//"); i >= 0 {
-		return s[:i]
-	}
-	return s
+	name, _, _ := splitName(s)
+	return unescapeName(name)
 }
@@ -0,0 +1,266 @@
+// Package portable checks that a tree can be checked out on every major
+// platform (Windows, macOS, Linux) and can rewrite names that cannot.
+package portable
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+// Diagnostic codes emitted by Check and Sanitize.
+const (
+	CodeReservedName     = "reserved-name"     // CON, AUX, COM1, ... (Windows)
+	CodeIllegalChar      = "illegal-char"      // < > : " | ? * or control characters (Windows)
+	CodeTrailingDot      = "trailing-dot"      // name ends with "." or " " (Windows strips it)
+	CodeNameTooLong      = "name-too-long"     // component longer than MaxName bytes
+	CodePathTooLong      = "path-too-long"     // path longer than MaxPath characters (Windows MAX_PATH)
+	CodeCaseCollision    = "case-collision"    // names differ only by case (Windows, macOS)
+	CodeUnicodeCollision = "unicode-collision" // names differ only by Unicode normalisation (macOS)
+)
+
+const (
+	MaxName = 255 // bytes per path component on ext4, APFS and NTFS
+	MaxPath = 260 // characters in a full path on Windows without long-path support
+)
+
+const illegalChars = `<>:"|?*`
+
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// isReserved reports whether name is a Windows device name. The extension
+// does not help: "aux.go" is just as reserved as "aux".
+func isReserved(name string) bool {
+	stem := name
+	if i := strings.Index(stem, "."); i >= 0 {
+		stem = stem[:i]
+	}
+	return reservedNames[strings.ToUpper(strings.TrimRight(stem, " "))]
+}
+
+func hasIllegalChar(name string) bool {
+	for _, r := range name {
+		if r < 0x20 || strings.ContainsRune(illegalChars, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// decompose returns s in (a Latin-only approximation of) NFD.
+func decompose(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if d, ok := decompositions[r]; ok {
+			b.WriteString(d)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// foldKey identifies names that case-insensitive, normalisation-insensitive
+// filesystems treat as the same file.
+func foldKey(name string) string {
+	return strings.ToLower(decompose(name))
+}
+
+// nameProblems lists what is wrong with a single path component.
+func nameProblems(name string) []string {
+	var codes []string
+	if isReserved(name) {
+		codes = append(codes, CodeReservedName)
+	}
+	if hasIllegalChar(name) {
+		codes = append(codes, CodeIllegalChar)
+	}
+	if name != "." && name != ".." && (strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ")) {
+		codes = append(codes, CodeTrailingDot)
+	}
+	if len(name) > MaxName {
+		codes = append(codes, CodeNameTooLong)
+	}
+	return codes
+}
+
+var problemText = map[string]string{
+	CodeReservedName: "is a reserved device name on Windows",
+	CodeIllegalChar:  `contains characters Windows does not allow (< > : " | ? * or control characters)`,
+	CodeTrailingDot:  "ends with a dot or space, which Windows silently drops",
+	CodeNameTooLong:  fmt.Sprintf("is longer than %d bytes", MaxName),
+}
+
+func warn(code string, line int, format string, args ...any) parser.Diagnostic {
+	return parser.Diagnostic{
+		Severity: parser.SeverityWarning,
+		Code:     code,
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// Check reports every node that would not survive a checkout on one of the
+// major platforms: reserved or illegal names, over-long names and paths, and
+// names that collide on case-insensitive or normalising filesystems.
+func Check(nodes []parser.Node) []parser.Diagnostic {
+	var diags []parser.Diagnostic
+	seen := make(map[string]bool)     // path prefixes already checked
+	folded := make(map[string]string) // fold key -> first path prefix with that key
+
+	for _, n := range nodes {
+		parts := strings.Split(n.Path, "/")
+		for i, name := range parts {
+			prefix := strings.Join(parts[:i+1], "/")
+			if seen[prefix] {
+				continue
+			}
+			seen[prefix] = true
+
+			for _, code := range nameProblems(name) {
+				diags = append(diags, warn(code, n.Line, "%q %s", prefix, problemText[code]))
+			}
+
+			key := foldKey(prefix)
+			first, ok := folded[key]
+			if !ok {
+				folded[key] = prefix
+				continue
+			}
+			code := CodeCaseCollision
+			if decompose(first) == decompose(prefix) {
+				code = CodeUnicodeCollision
+			}
+			diags = append(diags, warn(code, n.Line, "%q collides with %q on case-insensitive or normalising filesystems", prefix, first))
+		}
+
+		if l := utf8.RuneCountInString(n.Path); l > MaxPath {
+			diags = append(diags, warn(CodePathTooLong, n.Line, "%q is %d characters long (Windows limit: %d)", n.Path, l, MaxPath))
+		}
+	}
+	return diags
+}
+
+// Sanitize rewrites names so the tree can be checked out everywhere:
+// illegal characters become "_", trailing dots and spaces are dropped,
+// reserved names get a "_" suffix ("aux.go" -> "aux_.go"), over-long names
+// are shortened, and colliding names get a "~2", "~3", ... suffix.
+// Renaming a directory renames everything below it. Over-long paths cannot be
+// fixed automatically and are only reported.
+func Sanitize(nodes []parser.Node) ([]parser.Node, []parser.Diagnostic) {
+	var diags []parser.Diagnostic
+	renamed := make(map[string]string)          // original path prefix -> sanitized prefix
+	taken := make(map[string]map[string]string) // sanitized parent -> fold key -> child name
+
+	out := make([]parser.Node, 0, len(nodes))
+	for _, n := range nodes {
+		parts := strings.Split(n.Path, "/")
+		parent := ""
+		for i, name := range parts {
+			orig := strings.Join(parts[:i+1], "/")
+			if done, ok := renamed[orig]; ok {
+				parent = done
+				continue
+			}
+
+			fixed := fixName(name)
+			code := ""
+			if codes := nameProblems(name); len(codes) > 0 {
+				code = codes[0]
+			}
+
+			if taken[parent] == nil {
+				taken[parent] = make(map[string]string)
+			}
+			if other, ok := taken[parent][foldKey(fixed)]; ok {
+				code = CodeCaseCollision
+				if decompose(other) == decompose(fixed) {
+					code = CodeUnicodeCollision
+				}
+				fixed = uniqueName(fixed, taken[parent])
+			}
+			taken[parent][foldKey(fixed)] = fixed
+
+			full := fixed
+			if parent != "" {
+				full = parent + "/" + fixed
+			}
+			if fixed != name {
+				diags = append(diags, parser.Diagnostic{
+					Severity: parser.SeverityInfo,
+					Code:     code,
+					Line:     n.Line,
+					Message:  fmt.Sprintf("renamed %q to %q", orig, full),
+				})
+			}
+			renamed[orig] = full
+			parent = full
+		}
+
+		n.Path = parent
+		if l := utf8.RuneCountInString(n.Path); l > MaxPath {
+			diags = append(diags, warn(CodePathTooLong, n.Line, "%q is %d characters long (Windows limit: %d)", n.Path, l, MaxPath))
+		}
+		out = append(out, n)
+	}
+	return out, diags
+}
+
+// fixName rewrites a single path component into a portable one.
+func fixName(name string) string {
+	if name == "." || name == ".." {
+		return name
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(illegalChars, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimRight(name, ". ")
+	if name == "" {
+		name = "_"
+	}
+	if isReserved(name) {
+		stem, ext := splitExt(name)
+		name = stem + "_" + ext
+	}
+	if len(name) > MaxName {
+		stem, ext := splitExt(name)
+		if len(ext) > MaxName/2 {
+			stem, ext = name, ""
+		}
+		keep := MaxName - len(ext)
+		for keep > 0 && !utf8.RuneStart(stem[keep]) {
+			keep-- // do not cut a multi-byte character in half
+		}
+		name = stem[:keep] + ext
+	}
+	return name
+}
+
+// uniqueName appends "~2", "~3", ... until name no longer collides with taken.
+func uniqueName(name string, taken map[string]string) string {
+	stem, ext := splitExt(name)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s~%d%s", stem, i, ext)
+		if _, ok := taken[foldKey(candidate)]; !ok {
+			return candidate
+		}
+	}
+}
+
+// splitExt splits "name.tar.gz" into "name" and ".tar.gz"; dotfiles keep their name.
+func splitExt(name string) (string, string) {
+	i := strings.Index(name[1:], ".")
+	if i < 0 {
+		return name, ""
+	}
+	return name[:i+1], name[i+1:]
+}
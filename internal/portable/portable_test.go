@@ -0,0 +1,87 @@
+package portable
+
+import (
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+func codes(diags []parser.Diagnostic) map[string]int {
+	m := make(map[string]int)
+	for _, d := range diags {
+		m[d.Code]++
+	}
+	return m
+}
+
+func TestCheck(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "proj", Kind: parser.Dir, Line: 1},
+		{Path: "proj/aux.go", Kind: parser.File, Line: 2},
+		{Path: "proj/a:b.txt", Kind: parser.File, Line: 3},
+		{Path: "proj/notes.", Kind: parser.File, Line: 4},
+		{Path: "proj/Readme.md", Kind: parser.File, Line: 5},
+		{Path: "proj/README.md", Kind: parser.File, Line: 6},
+		{Path: "proj/caf\u00e9.md", Kind: parser.File, Line: 7},
+		{Path: "proj/cafe\u0301.md", Kind: parser.File, Line: 8},
+		{Path: "proj/main.go", Kind: parser.File, Line: 9},
+	}
+
+	got := codes(Check(nodes))
+	want := map[string]int{
+		CodeReservedName:     1,
+		CodeIllegalChar:      1,
+		CodeTrailingDot:      1,
+		CodeCaseCollision:    1,
+		CodeUnicodeCollision: 1,
+	}
+	for code, n := range want {
+		if got[code] != n {
+			t.Errorf("%s: got %d diagnostics, want %d (all: %v)", code, got[code], n, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected diagnostics: %v", got)
+	}
+}
+
+func TestCheck_Clean(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "src", Kind: parser.Dir},
+		{Path: "src/main.go", Kind: parser.File},
+		{Path: "src/auxiliary.go", Kind: parser.File},
+		{Path: "docs/README.md", Kind: parser.File},
+		{Path: "src/README.md", Kind: parser.File},
+	}
+	if diags := Check(nodes); len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "con", Kind: parser.Dir},
+		{Path: "con/x.txt", Kind: parser.File},
+		{Path: "what?.txt", Kind: parser.File},
+		{Path: "Readme.md", Kind: parser.File},
+		{Path: "README.md", Kind: parser.File},
+		{Path: "trailing.", Kind: parser.File},
+	}
+
+	out, diags := Sanitize(nodes)
+	want := []string{"con_", "con_/x.txt", "what_.txt", "Readme.md", "README~2.md", "trailing"}
+	if len(out) != len(want) {
+		t.Fatalf("got %d nodes, want %d", len(out), len(want))
+	}
+	for i, n := range out {
+		if n.Path != want[i] {
+			t.Errorf("node %d: got %q, want %q", i, n.Path, want[i])
+		}
+	}
+	if len(diags) != 4 {
+		t.Errorf("expected 4 rename diagnostics, got %v", diags)
+	}
+	if rest := Check(out); len(rest) != 0 {
+		t.Errorf("sanitized tree still has problems: %v", rest)
+	}
+}
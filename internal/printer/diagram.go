@@ -0,0 +1,224 @@
+package printer
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+// Diagram back-ends. They share the parent/child map of the tree printer and
+// number nodes in display order (n0, n1, ...), so the output is stable.
+
+// label returns the display name of a node, with a trailing slash on
+// directories and the target of a symlink.
+func label(n parser.Node) string {
+	name := baseName(n.Path)
+	switch n.Kind {
+	case parser.Dir:
+		name += "/"
+	case parser.Symlink:
+		name += " -> " + n.Target
+	}
+	return name
+}
+
+// edge is a parent/child pair of node numbers.
+type edge struct{ from, to int }
+
+// number walks the tree and returns nodes in display order, their depth, and
+// the parent/child edges between them.
+func number(nodes []parser.Node) ([]parser.Node, []int, []edge) {
+	var order []parser.Node
+	var depths []int
+	var edges []edge
+	ids := make(map[string]int)
+	walk(nodes, func(n parser.Node, depth int, _ []parser.Node) {
+		key := strings.TrimSuffix(n.Path, "/")
+		ids[key] = len(order)
+		if i := strings.LastIndex(key, "/"); i >= 0 {
+			if parent, ok := ids[key[:i]]; ok {
+				edges = append(edges, edge{parent, len(order)})
+			}
+		}
+		order = append(order, n)
+		depths = append(depths, depth)
+	})
+	return order, depths, edges
+}
+
+// --- Mermaid ---
+
+// mermaidText quotes a label for Mermaid, which has no backslash escapes.
+func mermaidText(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+func writeMermaid(b *strings.Builder, nodes []parser.Node) {
+	order, _, edges := number(nodes)
+	b.WriteString("graph TD\n")
+	var dirs, files, links []string
+	for i, n := range order {
+		// Boxes for directories, rounded boxes for files, flags for symlinks.
+		id := fmt.Sprintf("n%d", i)
+		switch n.Kind {
+		case parser.Dir:
+			fmt.Fprintf(b, "  %s[%s]\n", id, mermaidText(label(n)))
+			dirs = append(dirs, id)
+		case parser.Symlink:
+			fmt.Fprintf(b, "  %s>%s]\n", id, mermaidText(label(n)))
+			links = append(links, id)
+		default:
+			fmt.Fprintf(b, "  %s(%s)\n", id, mermaidText(label(n)))
+			files = append(files, id)
+		}
+	}
+	for _, e := range edges {
+		fmt.Fprintf(b, "  n%d --> n%d\n", e.from, e.to)
+	}
+	b.WriteString("  classDef dir fill:#fdf3d0,stroke:#c99a1e\n")
+	b.WriteString("  classDef file fill:#ffffff,stroke:#8a8f98\n")
+	b.WriteString("  classDef link fill:#eef4ff,stroke:#5b7db1,stroke-dasharray:4 2\n")
+	for _, c := range []struct {
+		name string
+		ids  []string
+	}{{"dir", dirs}, {"file", files}, {"link", links}} {
+		if len(c.ids) > 0 {
+			fmt.Fprintf(b, "  class %s %s\n", strings.Join(c.ids, ","), c.name)
+		}
+	}
+}
+
+// writeMindmap writes a Mermaid mindmap. A mindmap has exactly one root, so
+// several top-level entries hang off a "." node.
+func writeMindmap(b *strings.Builder, nodes []parser.Node) {
+	order, depths, _ := number(nodes)
+	b.WriteString("mindmap\n")
+	base := 1
+	if roots := countDepth(depths, 0); roots != 1 {
+		b.WriteString("  root((.))\n")
+		base = 2
+	}
+	for i, n := range order {
+		shape := "[%s]"
+		switch n.Kind {
+		case parser.File:
+			shape = "(%s)"
+		case parser.Symlink:
+			shape = "{{%s}}"
+		}
+		if depths[i] == 0 && base == 1 {
+			shape = "((%s))"
+		}
+		fmt.Fprintf(b, "%sn%d"+shape+"\n", strings.Repeat("  ", base+depths[i]), i, mermaidText(label(n)))
+	}
+}
+
+func countDepth(depths []int, d int) int {
+	n := 0
+	for _, x := range depths {
+		if x == d {
+			n++
+		}
+	}
+	return n
+}
+
+// --- Graphviz DOT ---
+
+func dotText(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func writeDOT(b *strings.Builder, nodes []parser.Node) {
+	order, _, edges := number(nodes)
+	b.WriteString("digraph tree {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\", fontsize=11, style=filled];\n")
+	for i, n := range order {
+		attrs := `shape=folder, fillcolor="#fdf3d0"`
+		switch n.Kind {
+		case parser.File:
+			attrs = `shape=note, fillcolor="#ffffff"`
+		case parser.Symlink:
+			attrs = `shape=cds, style="filled,dashed", fillcolor="#eef4ff"`
+		}
+		fmt.Fprintf(b, "  n%d [label=%s, %s];\n", i, dotText(label(n)), attrs)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(b, "  n%d -> n%d;\n", e.from, e.to)
+	}
+	b.WriteString("}\n")
+}
+
+// --- SVG ---
+
+// SVG layout, in pixels.
+const (
+	svgRow    = 24 // height of one entry
+	svgIndent = 20 // horizontal step per level
+	svgMargin = 12
+	svgGlyph  = 16
+	svgChar   = 8 // approximate advance of one character
+)
+
+const svgDefs = `  <defs>
+    <symbol id="folder" viewBox="0 0 16 16"><path d="M1 3.5h5l1.5 1.5H15v8.5H1z" fill="#f4c542" stroke="#c99a1e"/></symbol>
+    <symbol id="file" viewBox="0 0 16 16"><path d="M3 1.5h6.5L13 5v9.5H3z" fill="#ffffff" stroke="#8a8f98"/><path d="M9.5 1.5V5H13" fill="none" stroke="#8a8f98"/></symbol>
+    <symbol id="link" viewBox="0 0 16 16"><path d="M3 1.5h6.5L13 5v9.5H3z" fill="#eef4ff" stroke="#5b7db1" stroke-dasharray="2 1"/><path d="M5.5 11.5L10 7M7 7h3v3" fill="none" stroke="#5b7db1"/></symbol>
+  </defs>
+`
+
+// writeSVG draws the tree as a standalone SVG image: one row per entry with
+// folder, file and symlink glyphs and connector lines, like the tree printer.
+func writeSVG(b *strings.Builder, nodes []parser.Node) {
+	order, depths, edges := number(nodes)
+
+	width := 0
+	for i, n := range order {
+		w := svgMargin + depths[i]*svgIndent + svgGlyph + 6 + utf8.RuneCountInString(label(n))*svgChar + svgMargin
+		width = max(width, w)
+	}
+	height := 2*svgMargin + len(order)*svgRow
+
+	glyphX := func(i int) int { return svgMargin + depths[i]*svgIndent }
+	midY := func(i int) int { return svgMargin + i*svgRow + svgRow/2 }
+
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="ui-monospace, SFMono-Regular, Menlo, Consolas, monospace" font-size="13">`+"\n",
+		width, height, width, height)
+	b.WriteString(svgDefs)
+	b.WriteString(`  <rect width="100%" height="100%" fill="#ffffff"/>` + "\n")
+
+	// Connectors: a vertical line below each directory down to its last
+	// child, and a short horizontal line into every child.
+	b.WriteString(`  <g stroke="#c8ccd2" fill="none">` + "\n")
+	last := make(map[int]int)
+	for _, e := range edges {
+		last[e.from] = e.to
+		x := glyphX(e.from) + svgGlyph/2
+		fmt.Fprintf(b, `    <path d="M%d %dH%d"/>`+"\n", x, midY(e.to), glyphX(e.to)-2)
+	}
+	for i := range order {
+		if to, ok := last[i]; ok {
+			x := glyphX(i) + svgGlyph/2
+			fmt.Fprintf(b, `    <path d="M%d %dV%d"/>`+"\n", x, midY(i)+svgGlyph/2, midY(to))
+		}
+	}
+	b.WriteString("  </g>\n")
+
+	for i, n := range order {
+		glyph := "folder"
+		switch n.Kind {
+		case parser.File:
+			glyph = "file"
+		case parser.Symlink:
+			glyph = "link"
+		}
+		x, y := glyphX(i), midY(i)
+		fmt.Fprintf(b, `  <use href="#%s" x="%d" y="%d" width="%d" height="%d"/>`+"\n", glyph, x, y-svgGlyph/2, svgGlyph, svgGlyph)
+		fmt.Fprintf(b, `  <text x="%d" y="%d" dominant-baseline="central" fill="#24292f">%s</text>`+"\n", x+svgGlyph+6, y, html.EscapeString(label(n)))
+	}
+	b.WriteString("</svg>\n")
+}
@@ -0,0 +1,116 @@
+package printer
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+var diagramNodes = []parser.Node{
+	{Path: "src", Kind: parser.Dir},
+	{Path: "src/main.go", Kind: parser.File},
+	{Path: `src/say "hi".txt`, Kind: parser.File},
+	{Path: "README.md", Kind: parser.File},
+}
+
+func render(t *testing.T, format string) string {
+	t.Helper()
+	var b strings.Builder
+	if err := Render(&b, diagramNodes, format, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestRender_Mermaid(t *testing.T) {
+	out := render(t, "mermaid")
+	for _, want := range []string{
+		"graph TD\n",
+		`  n0["src/"]`,
+		`  n2("say #quot;hi#quot;.txt")`,
+		"  n0 --> n1\n",
+		"  n0 --> n2\n",
+		"  class n0 dir\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "--> n3") {
+		t.Errorf("top-level README.md should have no parent:\n%s", out)
+	}
+}
+
+func TestRender_Mindmap(t *testing.T) {
+	out := render(t, "mindmap")
+	want := "mindmap\n  root((.))\n    n0[\"src/\"]\n      n1(\"main.go\")\n"
+	if !strings.HasPrefix(out, want) {
+		t.Errorf("two top-level entries need a shared root, got:\n%s", out)
+	}
+}
+
+func TestRender_DOT(t *testing.T) {
+	out := render(t, "dot")
+	for _, want := range []string{
+		"digraph tree {\n",
+		`  n0 [label="src/", shape=folder`,
+		`  n2 [label="say \"hi\".txt", shape=note`,
+		"  n0 -> n1;\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRender_SVG(t *testing.T) {
+	out := render(t, "svg")
+
+	var doc struct {
+		XMLName xml.Name
+		Uses    []struct {
+			Href string `xml:"href,attr"`
+		} `xml:"use"`
+		Texts []string `xml:"text"`
+	}
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("output is not well-formed XML: %v\n%s", err, out)
+	}
+	if doc.XMLName.Local != "svg" || len(doc.Uses) != len(diagramNodes) {
+		t.Fatalf("expected an <svg> with %d glyphs, got <%s> with %d", len(diagramNodes), doc.XMLName.Local, len(doc.Uses))
+	}
+	if doc.Uses[0].Href != "#folder" || doc.Uses[1].Href != "#file" {
+		t.Errorf("unexpected glyphs: %+v", doc.Uses)
+	}
+	if doc.Texts[2] != `say "hi".txt` {
+		t.Errorf("label not escaped and restored: %q", doc.Texts[2])
+	}
+}
+
+func TestRender_DiagramSymlinks(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "shared", Kind: parser.Dir},
+		{Path: "config", Kind: parser.Symlink, Target: "shared"},
+	}
+	for format, want := range map[string][]string{
+		"mermaid": {`  n1>"config -> shared"]`, "  class n1 link\n"},
+		"mindmap": {`n1{{"config -> shared"}}`},
+		"dot":     {`n1 [label="config -> shared", shape=cds`},
+		"svg":     {`<use href="#link"`},
+	} {
+		var b strings.Builder
+		if err := Render(&b, nodes, format, Options{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range want {
+			if !strings.Contains(b.String(), w) {
+				t.Errorf("%s: missing %q in:\n%s", format, w, b.String())
+			}
+		}
+		if format == "mermaid" && strings.Contains(b.String(), "class n1 file") {
+			t.Errorf("mermaid: symlink in the file class:\n%s", b.String())
+		}
+	}
+}
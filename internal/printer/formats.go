@@ -0,0 +1,314 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+// Formats lists the output formats Render supports.
+var Formats = []string{"tree", "markdown", "json", "yaml", "paths", "html", "mermaid", "mindmap", "dot", "svg"}
+
+// Render writes nodes to w in the given format. The text formats (tree,
+// markdown, json, yaml, paths, html) parse back into the same nodes, and
+// "json" and "yaml" also keep file contents, modes and templates. The
+// diagram formats (mermaid, mindmap, dot, svg) are for documentation only.
+func Render(w io.Writer, nodes []parser.Node, format string, opts Options) error {
+	var b strings.Builder
+	switch format {
+	case "", "tree":
+		writeTree(&b, nodes, opts)
+	case "markdown", "md":
+		writeMarkdown(&b, nodes)
+	case "json":
+		writeJSON(&b, nodes)
+	case "yaml", "yml":
+		writeYAML(&b, nodes)
+	case "paths":
+		for _, n := range nodes {
+			p := parser.QuotePath(n.Path)
+			if n.Kind == parser.Dir && !strings.HasSuffix(p, "/") {
+				p += "/"
+			}
+			if n.Kind == parser.Symlink {
+				p += " -> " + parser.QuotePath(n.Target)
+			}
+			b.WriteString(withMode(p, n) + "\n")
+		}
+	case "html":
+		writeHTML(&b, nodes)
+	case "mermaid":
+		writeMermaid(&b, nodes)
+	case "mindmap":
+		writeMindmap(&b, nodes)
+	case "dot", "graphviz":
+		writeDOT(&b, nodes)
+	case "svg":
+		writeSVG(&b, nodes)
+	default:
+		return fmt.Errorf("unknown format %q (want one of: %s)", format, strings.Join(Formats, ", "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// walk visits nodes depth-first in display order.
+func walk(nodes []parser.Node, visit func(n parser.Node, depth int, children []parser.Node)) {
+	roots, childrenMap := buildChildren(nodes)
+	var rec func(list []parser.Node, depth int)
+	rec = func(list []parser.Node, depth int) {
+		for _, n := range list {
+			children := childrenMap[strings.TrimSuffix(n.Path, "/")]
+			visit(n, depth, children)
+			rec(children, depth+1)
+		}
+	}
+	rec(roots, 0)
+}
+
+// --- Markdown ---
+
+// markdownSpecial are characters that could turn a name into emphasis or a link.
+const markdownSpecial = "*_`[]<>#|\\"
+
+func writeMarkdown(b *strings.Builder, nodes []parser.Node) {
+	var lines []commentedLine
+	walk(nodes, func(n parser.Node, depth int, _ []parser.Node) {
+		// A code span keeps the name literal for Markdown and for the parser.
+		name := baseName(n.Path)
+		switch {
+		case strings.Contains(name, "`"):
+			name = parser.QuoteName(name)
+		case strings.ContainsAny(name, markdownSpecial) || parser.QuoteName(name) != name:
+			name = "`" + name + "`"
+		}
+		if n.Kind == parser.Dir {
+			name += "/"
+		}
+		name = withMode(name, n)
+		if n.Kind == parser.Symlink {
+			name += " -> " + parser.QuoteName(n.Target)
+		}
+		lines = append(lines, commentedLine{strings.Repeat("  ", depth) + "- " + name, n.Description})
+	})
+	writeCommented(b, lines)
+}
+
+// --- JSON and YAML ---
+
+// fileKeys mirror the keys of a file object in a structured spec. A directory
+// whose children all carry these names is written as "name/" so it is not
+// mistaken for a file.
+var fileKeys = map[string]bool{"content": true, "mode": true, "template": true, "description": true, "target": true}
+
+func looksLikeFileObject(children []parser.Node) bool {
+	if len(children) == 0 {
+		return false
+	}
+	for _, c := range children {
+		if !fileKeys[baseName(c.Path)] || c.Kind != parser.File || c.Content == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// fileFields returns the options of a file or symlink in key order, or nil
+// for a plain file. The description is left out when the caller writes it as
+// a comment.
+func fileFields(n parser.Node, withDesc bool) [][2]string {
+	var fields [][2]string
+	if n.Kind == parser.Symlink {
+		fields = append(fields, [2]string{"target", n.Target})
+	}
+	desc := withDesc && n.Description != ""
+	if n.Content != "" && (n.Mode != 0 || n.Template != "" || desc) {
+		fields = append(fields, [2]string{"content", n.Content})
+	}
+	if n.Mode != 0 {
+		fields = append(fields, [2]string{"mode", fmt.Sprintf("%04o", uint32(n.Mode.Perm()))})
+	}
+	if n.Template != "" {
+		fields = append(fields, [2]string{"template", n.Template})
+	}
+	if desc {
+		fields = append(fields, [2]string{"description", n.Description})
+	}
+	return fields
+}
+
+func writeJSON(b *strings.Builder, nodes []parser.Node) {
+	roots, childrenMap := buildChildren(nodes)
+	quote := func(s string) string {
+		var q strings.Builder
+		enc := json.NewEncoder(&q)
+		enc.SetEscapeHTML(false)
+		enc.Encode(s)
+		return strings.TrimSuffix(q.String(), "\n")
+	}
+
+	var obj func(list []parser.Node, indent string)
+	obj = func(list []parser.Node, indent string) {
+		if len(list) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for i, n := range list {
+			children := childrenMap[strings.TrimSuffix(n.Path, "/")]
+			name := baseName(n.Path)
+			if n.Kind == parser.Dir && looksLikeFileObject(children) {
+				name += "/"
+			}
+			fmt.Fprintf(b, "%s  %s: ", indent, quote(name))
+
+			switch fields := fileFields(n, true); {
+			case n.Kind == parser.Dir:
+				obj(children, indent+"  ")
+			case fields != nil:
+				b.WriteString("{")
+				for j, f := range fields {
+					if j > 0 {
+						b.WriteString(", ")
+					}
+					fmt.Fprintf(b, "%s: %s", quote(f[0]), quote(f[1]))
+				}
+				b.WriteString("}")
+			case n.Content != "":
+				b.WriteString(quote(n.Content))
+			default:
+				b.WriteString("null")
+			}
+			if i < len(list)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "}")
+	}
+	obj(roots, "")
+	b.WriteString("\n")
+}
+
+func writeYAML(b *strings.Builder, nodes []parser.Node) {
+	roots, childrenMap := buildChildren(nodes)
+	if len(roots) == 0 {
+		b.WriteString("{}\n")
+		return
+	}
+
+	var mapping func(list []parser.Node, indent string)
+	mapping = func(list []parser.Node, indent string) {
+		for _, n := range list {
+			children := childrenMap[strings.TrimSuffix(n.Path, "/")]
+			name := baseName(n.Path)
+			if n.Kind == parser.Dir && looksLikeFileObject(children) {
+				name += "/"
+			}
+			fmt.Fprintf(b, "%s%s:", indent, yamlKey(name))
+
+			// A description is a comment on the key line; the parser keeps it.
+			comment := ""
+			if n.Description != "" {
+				comment = "  # " + oneLine(n.Description)
+			}
+			switch fields := fileFields(n, false); {
+			case n.Kind == parser.Dir && len(children) == 0:
+				b.WriteString(" {}" + comment + "\n")
+			case n.Kind == parser.Dir:
+				b.WriteString(comment + "\n")
+				mapping(children, indent+"  ")
+			case fields != nil:
+				b.WriteString(comment + "\n")
+				for _, f := range fields {
+					fmt.Fprintf(b, "%s  %s:", indent, f[0])
+					writeYAMLString(b, f[1], indent+"    ")
+				}
+			case n.Content != "":
+				writeYAMLString(b, n.Content, indent+"  ", comment)
+			default:
+				b.WriteString(comment + "\n")
+			}
+		}
+	}
+	mapping(roots, "")
+}
+
+// yamlKey quotes a name unless it is safe as a plain YAML key.
+func yamlKey(name string) string {
+	if name == "" || strings.ContainsAny(name, ":#\"'\t") || strings.ContainsAny(name[:1], "-?,[]{}&*!|>%@` ") ||
+		strings.HasSuffix(name, " ") {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+// writeYAMLString writes " value\n" after a key: a block scalar for
+// multi-line text, a quoted string otherwise. An optional comment goes on
+// the key line.
+func writeYAMLString(b *strings.Builder, s, indent string, comment ...string) {
+	c := strings.Join(comment, "")
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	plain := strings.Contains(s, "\n") && !strings.ContainsAny(s, "\r\t") && !strings.HasPrefix(s, " ")
+	for _, l := range lines {
+		plain = plain && l == strings.TrimRight(l, " ")
+	}
+	if !plain {
+		fmt.Fprintf(b, " %s%s\n", strconv.Quote(s), c)
+		return
+	}
+
+	header := "|"
+	switch {
+	case !strings.HasSuffix(s, "\n"):
+		header = "|-"
+	case strings.HasSuffix(s, "\n\n"):
+		header = "|+"
+	}
+	fmt.Fprintf(b, " %s%s\n", header, c)
+	for _, l := range lines {
+		if l == "" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(indent + l + "\n")
+	}
+}
+
+// --- HTML ---
+
+func writeHTML(b *strings.Builder, nodes []parser.Node) {
+	roots, childrenMap := buildChildren(nodes)
+
+	var list func(items []parser.Node, indent string)
+	list = func(items []parser.Node, indent string) {
+		b.WriteString(indent + "<ul>\n")
+		for _, n := range items {
+			name := html.EscapeString(baseName(n.Path))
+			if n.Kind == parser.Dir {
+				name += "/"
+			}
+			name = withMode(name, n)
+			if n.Kind == parser.Symlink {
+				name += " -&gt; " + html.EscapeString(n.Target)
+			}
+			children := childrenMap[strings.TrimSuffix(n.Path, "/")]
+			if len(children) == 0 {
+				fmt.Fprintf(b, "%s  <li>%s</li>\n", indent, name)
+				continue
+			}
+			fmt.Fprintf(b, "%s  <li>%s\n", indent, name)
+			list(children, indent+"    ")
+			fmt.Fprintf(b, "%s  </li>\n", indent)
+		}
+		b.WriteString(indent + "</ul>\n")
+	}
+	if len(roots) > 0 {
+		list(roots, "")
+	}
+}
@@ -0,0 +1,123 @@
+package printer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+)
+
+func TestRender_RoundTrip(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "proj", Kind: parser.Dir},
+		{Path: "proj/__init__.py", Kind: parser.File},
+		{Path: "proj/src", Kind: parser.Dir},
+		{Path: "proj/src/main.go", Kind: parser.File},
+		{Path: "proj/src/a & b.txt", Kind: parser.File},
+		{Path: "proj/src/{a,b}.txt", Kind: parser.File},
+		{Path: "proj/My Docs", Kind: parser.Dir},
+		{Path: "proj/My Docs/a # b.txt", Kind: parser.File},
+		{Path: "proj/My Docs/a | b.txt", Kind: parser.File},
+		{Path: "proj/My Docs/\"quoted\".txt", Kind: parser.File},
+		{Path: "proj/My Docs/it's.md", Kind: parser.File},
+		{Path: "proj/My Docs/main.go", Kind: parser.Symlink, Target: "../src/main.go"},
+		{Path: "proj/docs", Kind: parser.Symlink, Target: "My Docs"},
+		{Path: "proj/empty", Kind: parser.Dir},
+		{Path: "proj/README.md", Kind: parser.File, Mode: 0600},
+	}
+
+	var want strings.Builder
+	if err := Render(&want, nodes, "tree", Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"tree", "markdown", "json", "yaml", "paths", "html"} {
+		t.Run(format, func(t *testing.T) {
+			var out strings.Builder
+			if err := Render(&out, nodes, format, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			res := parser.Parse(out.String())
+
+			var got strings.Builder
+			Render(&got, res.Nodes, "tree", Options{})
+			if got.String() != want.String() {
+				t.Errorf("round trip through %s changed the tree:\n%s\nrendered as:\n%s", format, got.String(), out.String())
+			}
+		})
+	}
+}
+
+func TestRender_SpecFieldsRoundTrip(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "bin", Kind: parser.Dir},
+		{Path: "bin/run.sh", Kind: parser.File, Content: "#!/bin/sh\n\necho hi\n", Mode: 0755},
+		{Path: "notes.txt", Kind: parser.File, Content: "one line"},
+		{Path: "Containerfile", Kind: parser.File, Template: "Dockerfile"},
+		{Path: "odd", Kind: parser.Dir},
+		{Path: "odd/content", Kind: parser.File, Content: "not a file object\n"},
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			var out strings.Builder
+			if err := Render(&out, nodes, format, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			res := parser.Parse(out.String())
+			if res.Format != format {
+				t.Fatalf("output read back as %q:\n%s", res.Format, out.String())
+			}
+
+			got := make(map[string]parser.Node)
+			for _, n := range res.Nodes {
+				got[n.Path] = n
+			}
+			for _, n := range nodes {
+				g := got[n.Path]
+				if g.Kind != n.Kind || g.Content != n.Content || g.Mode != n.Mode || g.Template != n.Template {
+					t.Errorf("%s: got %+v, want %+v\n%s", n.Path, g, n, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRender_Descriptions(t *testing.T) {
+	nodes := []parser.Node{
+		{Path: "app", Kind: parser.Dir},
+		{Path: "app/cmd", Kind: parser.Dir, Description: "entry points"},
+		{Path: "app/cmd/main.go", Kind: parser.File, Description: "wires\nit up"},
+		{Path: "app/go.mod", Kind: parser.File},
+	}
+
+	var out strings.Builder
+	if err := Render(&out, nodes, "tree", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "└── app/\n" +
+		"    ├── cmd/         # entry points\n" +
+		"    │   └── main.go  # wires it up\n" +
+		"    └── go.mod\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	for _, format := range []string{"tree", "markdown", "yaml"} {
+		out.Reset()
+		if err := Render(&out, nodes, format, Options{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range parser.Parse(out.String()).Nodes {
+			if n.Path == "app/cmd/main.go" && n.Description != "wires it up" {
+				t.Errorf("%s: description read back as %q:\n%s", format, n.Description, out.String())
+			}
+		}
+	}
+}
+
+func TestRender_UnknownFormat(t *testing.T) {
+	if err := Render(&strings.Builder{}, nil, "pdf", Options{}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -3,8 +3,11 @@ package printer
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/cytificlabs/tr2rl/internal/parser"
 )
@@ -23,19 +26,54 @@ func PrintTree(nodes []parser.Node) {
 
 // PrintTreeWithOptions prints the tree with specific formatting options.
 func PrintTreeWithOptions(nodes []parser.Node, opts Options) {
-	if len(nodes) == 0 {
-		return
+	writeTree(os.Stdout, nodes, opts)
+}
+
+// writeTree draws nodes as a tree with box-drawing (or ASCII) markers.
+func writeTree(w io.Writer, nodes []parser.Node, opts Options) {
+	roots, childrenMap := buildChildren(nodes)
+	var lines []commentedLine
+	for i, root := range roots {
+		printNode(&lines, root, "", i == len(roots)-1, childrenMap, opts)
 	}
+	writeCommented(w, lines)
+}
 
-	// 1. Build a Tree map structure for printing
-	childrenMap := make(map[string][]parser.Node)
-	roots := make([]parser.Node, 0)
-	nodeMap := make(map[string]parser.Node)
+// commentedLine is an output line with an optional node description.
+type commentedLine struct {
+	text, desc string
+}
 
-	for _, n := range nodes {
-		key := strings.TrimSuffix(n.Path, "/")
-		nodeMap[key] = n
+// writeCommented writes lines, with descriptions as "# ..." comments lined up
+// two spaces after the longest described line. The parser reads them back.
+func writeCommented(w io.Writer, lines []commentedLine) {
+	width := 0
+	for _, l := range lines {
+		if l.desc != "" {
+			width = max(width, utf8.RuneCountInString(l.text))
+		}
 	}
+	for _, l := range lines {
+		if l.desc == "" {
+			fmt.Fprintln(w, l.text)
+			continue
+		}
+		pad := strings.Repeat(" ", width-utf8.RuneCountInString(l.text))
+		fmt.Fprintf(w, "%s%s  # %s\n", l.text, pad, oneLine(l.desc))
+	}
+}
+
+// oneLine folds a multi-line description onto one line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// buildChildren groups nodes by parent directory. Roots and every list of
+// children are sorted (directories first, then by name); the map is keyed by
+// the parent's path without a trailing slash.
+func buildChildren(nodes []parser.Node) ([]parser.Node, map[string][]parser.Node) {
+	childrenMap := make(map[string][]parser.Node)
+	roots := make([]parser.Node, 0)
 
 	for _, n := range nodes {
 		key := strings.TrimSuffix(n.Path, "/")
@@ -49,16 +87,34 @@ func PrintTreeWithOptions(nodes []parser.Node, opts Options) {
 	}
 
 	sortNodes(roots)
+	for _, children := range childrenMap {
+		sortNodes(children)
+	}
+	return roots, childrenMap
+}
 
-	for i, root := range roots {
-		printNode(root, "", i == len(roots)-1, childrenMap, opts)
+// baseName returns the last element of a node path, without a trailing slash.
+func baseName(p string) string {
+	p = strings.TrimSuffix(p, "/")
+	if idx := strings.LastIndex(p, "/"); idx != -1 {
+		return p[idx+1:]
 	}
+	return p
 }
 
-func printNode(node parser.Node, prefix string, isLast bool, childrenMap map[string][]parser.Node, opts Options) {
+// withMode appends the mode annotation of n, such as " [755]", to name.
+// A symlink has no mode of its own.
+func withMode(name string, n parser.Node) string {
+	if m := parser.FormatMode(n.Mode); m != "" && n.Kind != parser.Symlink {
+		return name + " " + m
+	}
+	return name
+}
+
+func printNode(lines *[]commentedLine, node parser.Node, prefix string, isLast bool, childrenMap map[string][]parser.Node, opts Options) {
 	// Markers
 	var marker, link, noLink string
-	
+
 	if opts.Style == "ascii" {
 		// ASCII Style:
 		// |-- child
@@ -85,17 +141,16 @@ func printNode(node parser.Node, prefix string, isLast bool, childrenMap map[str
 		noLink = "    "
 	}
 
-	name := node.Path
-	if idx := strings.LastIndex(strings.TrimSuffix(name, "/"), "/"); idx != -1 {
-		name = strings.TrimSuffix(name, "/")
-		name = name[idx+1:]
-	}
-	name = strings.TrimSuffix(name, "/")
+	name := baseName(node.Path)
 	if node.Kind == parser.Dir {
 		name += "/"
 	}
+	name = withMode(parser.QuoteName(name), node)
+	if node.Kind == parser.Symlink {
+		name += " -> " + parser.QuoteName(node.Target)
+	}
 
-	fmt.Printf("%s%s%s\n", prefix, marker, name)
+	*lines = append(*lines, commentedLine{prefix + marker + name, node.Description})
 
 	// Calculate prefix for children
 	childPrefix := prefix
@@ -107,10 +162,9 @@ func printNode(node parser.Node, prefix string, isLast bool, childrenMap map[str
 
 	key := strings.TrimSuffix(node.Path, "/")
 	children := childrenMap[key]
-	sortNodes(children)
 
 	for i, child := range children {
-		printNode(child, childPrefix, i == len(children)-1, childrenMap, opts)
+		printNode(lines, child, childPrefix, i == len(children)-1, childrenMap, opts)
 	}
 }
 
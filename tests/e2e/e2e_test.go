@@ -186,3 +186,85 @@ func TestFormat_MixedIndent(t *testing.T) {
 		t.Errorf("Broken structure! header and src should be siblings. Output:\n%s", output)
 	}
 }
+
+func TestScan_RoundTrip(t *testing.T) {
+	// Build a structure, scan it back, and make sure the spec re-parses to the same paths.
+	input := `app/
+├── cmd/
+│   └── main.go
+├── empty/
+├── Makefile
+└── README.md`
+
+	tmpInput := filepath.Join(t.TempDir(), "spec.tree")
+	os.WriteFile(tmpInput, []byte(input), 0644)
+	outputDir := t.TempDir()
+
+	if out, err := runCLI("build", tmpInput, outputDir); err != nil {
+		t.Fatalf("Build failed: %v\nOutput: %s", err, out)
+	}
+
+	scanned, err := runCLI("scan", filepath.Join(outputDir, "app"))
+	if err != nil {
+		t.Fatalf("Scan failed: %v\nOutput: %s", err, scanned)
+	}
+
+	scanFile := filepath.Join(t.TempDir(), "scanned.tree")
+	os.WriteFile(scanFile, []byte(scanned), 0644)
+	spec, err := runCLI("spec", scanFile)
+	if err != nil {
+		t.Fatalf("Spec failed: %v\nOutput: %s", err, spec)
+	}
+
+	expected := "cmd/\ncmd/main.go\nempty/\nMakefile\nREADME.md"
+	if spec != expected {
+		t.Errorf("Scan output did not round-trip.\nExpected:\n%s\nGot:\n%s", expected, spec)
+	}
+}
+
+func TestCheck_ExitCode(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "layout.tree")
+	os.WriteFile(spec, []byte("├── src/\n│   └── main.go\n└── README.md\n"), 0644)
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "main.go"), nil, 0644)
+
+	out, err := runCLI("check", spec, dir)
+	if err == nil {
+		t.Fatalf("expected non-zero exit for a missing README.md. Output:\n%s", out)
+	}
+	if !strings.Contains(out, "missing") || !strings.Contains(out, "README.md") {
+		t.Errorf("expected README.md to be reported missing. Output:\n%s", out)
+	}
+
+	os.WriteFile(filepath.Join(dir, "README.md"), nil, 0644)
+	if out, err := runCLI("check", spec, dir); err != nil {
+		t.Errorf("expected a clean check. Error: %v\nOutput:\n%s", err, out)
+	}
+}
+
+func TestApply_FromAnotherDirectory(t *testing.T) {
+	work := t.TempDir()
+	other := filepath.Join(work, "other")
+	os.MkdirAll(other, 0755)
+	os.WriteFile(filepath.Join(work, "spec.tree"), []byte("app/\n  main.go\n"), 0644)
+
+	build := exec.Command(binaryPath, "build", "spec.tree", "./out", "--plan-out", "plan.json")
+	build.Dir = work
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build --plan-out failed: %v\n%s", err, out)
+	}
+
+	apply := exec.Command(binaryPath, "apply", filepath.Join(work, "plan.json"))
+	apply.Dir = other
+	if out, err := apply.CombinedOutput(); err != nil {
+		t.Fatalf("apply failed: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(work, "out", "app", "main.go")); err != nil {
+		t.Errorf("plan was not applied where it was made: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(other, "out")); !os.IsNotExist(err) {
+		t.Error("plan was applied relative to the working directory")
+	}
+}
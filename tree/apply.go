@@ -0,0 +1,104 @@
+package tree
+
+import (
+	"context"
+	"io"
+	"io/fs"
+
+	"github.com/cytificlabs/tr2rl/internal/archive"
+	ifs "github.com/cytificlabs/tr2rl/internal/fs"
+)
+
+// Dest is where Apply writes a tree. Names are slash paths relative to the
+// root of the build, and a directory is always made before anything in it.
+// A Dest that is also an FS is planned against what it already holds.
+type Dest interface {
+	Mkdir(name string, perm fs.FileMode) error
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// FS is a Dest that can be inspected: existing files are skipped (or
+// replaced with Force) and a file in the way of a directory is a conflict.
+type FS interface {
+	Dest
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// Linker is a Dest that can hold symlinks. Applying a tree with symlinks
+// needs one; DirFS, MemFS and Archive are Linkers.
+type Linker interface {
+	Symlink(target, name string) error
+}
+
+// DirFS returns the FS for a directory on disk, which Apply creates (with
+// its parents) if it does not exist yet. Paths that escape it, through ".."
+// or an existing symlink, are conflicts unless AllowOutside is set. Unlike
+// the CLI's build, Apply into DirFS keeps no undo journal.
+func DirFS(root string) FS {
+	return ifs.DirFS(root)
+}
+
+// MemFS is an FS held in memory; see NewMemFS and NewOverlay.
+type MemFS = ifs.MemFS
+
+// Change is one entry written to a MemFS, as listed by MemFS.Changes.
+type Change = ifs.Change
+
+// NewMemFS returns an empty in-memory FS, e.g. for tests.
+func NewMemFS() *MemFS {
+	return ifs.NewMemFS()
+}
+
+// NewOverlay returns an in-memory FS on top of base, which is only read.
+// Applying to it and calling Changes previews a build into base.
+func NewOverlay(base fs.FS) *MemFS {
+	return ifs.NewOverlay(base)
+}
+
+// Archive streams a build into a .zip or .tar(.gz); see NewArchive.
+type Archive = archive.Writer
+
+// NewArchive starts an archive in format "zip", "tar" or "tar.gz" on w.
+// Close the Archive after Apply to finish it.
+func NewArchive(w io.Writer, format string) (*Archive, error) {
+	return archive.NewWriter(w, format)
+}
+
+// ApplyOptions controls Apply.
+type ApplyOptions struct {
+	Force        bool      // replace files that already exist in an FS
+	Populate     bool      // fill files without content with boilerplate for their type
+	AllowOutside bool      // allow paths that resolve outside the destination
+	DryRun       bool      // plan only: write the plan to Log and change nothing
+	Log          io.Writer // progress lines such as "[OK] Created src/main.go"; nil discards them
+}
+
+// Apply builds the tree into dest. The whole tree is planned first and
+// nothing is written if the plan has conflicts. Cancelling ctx stops the
+// build between two writes; what was written so far stays.
+func (t *Tree) Apply(ctx context.Context, dest Dest, opts ApplyOptions) error {
+	nodes := t.flat()
+	fopts := ifs.ApplyOptions{Force: opts.Force, Populate: opts.Populate, AllowOutside: opts.AllowOutside}
+
+	plan := ifs.NewEmptyPlan(nodes, fopts)
+	if fsys, ok := dest.(FS); ok {
+		var err error
+		if plan, err = ifs.NewPlanFS(fsys, nodes, fopts); err != nil {
+			return err
+		}
+	}
+
+	if opts.DryRun {
+		if opts.Log != nil {
+			plan.Print(opts.Log)
+		}
+		return nil
+	}
+	return plan.ExecuteTo(dest, ifs.ExecuteOptions{
+		Context:      ctx,
+		Log:          opts.Log,
+		NoRollback:   true,
+		NoJournal:    true,
+		AllowOutside: opts.AllowOutside,
+	})
+}
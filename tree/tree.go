@@ -0,0 +1,226 @@
+// Package tree is the Go API of tr2rl: parse a tree spec (a text tree, a
+// path list, tool output, or a JSON/YAML layout), inspect or edit it, render
+// it in any of the formats the CLI writes, and build it into a directory, an
+// archive or memory.
+//
+//	t, err := tree.Parse(strings.NewReader("app/\n  main.go\n"), tree.Options{})
+//	if err != nil { ... }
+//	err = t.Apply(ctx, tree.DirFS("./out"), tree.ApplyOptions{Populate: true})
+//
+// Nothing in this package prints: diagnostics are returned on the Tree and
+// progress only goes to ApplyOptions.Log. The API is stable and follows the
+// module's semantic version; the CLI is built from the same internals.
+package tree
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/cytificlabs/tr2rl/internal/parser"
+	"github.com/cytificlabs/tr2rl/internal/printer"
+)
+
+// Kind says whether a node is a file, a directory or a symlink.
+type Kind string
+
+const (
+	File    Kind = "file"
+	Dir     Kind = "dir"
+	Symlink Kind = "symlink"
+)
+
+// Node is one file, directory or symlink. Name is the last element of Path.
+type Node struct {
+	Name string
+	Path string // slash path from the root, e.g. "src/main.go"
+	Kind Kind
+
+	Content  string      // file contents given by the spec; empty means none
+	Mode     fs.FileMode // permission bits given by the spec; 0 means the default
+	Template string      // file whose --populate boilerplate this file gets
+	Size     int64       // size recorded by a listing such as tree -s; 0 if unknown
+	Line     int         // 1-based input line, 0 if the node was added in code
+	Target   string      // where a symlink points, relative to its directory, e.g. "../shared"
+
+	// Description is the inline comment of the node, e.g. "entry point" for
+	// "main.go  # entry point". Render writes it back as a comment.
+	Description string
+
+	Children []*Node // directories only, in input order
+}
+
+// Diagnostic is something the parser noticed or guessed about the input.
+type Diagnostic struct {
+	Severity string // "info", "warning" or "error"
+	Code     string // stable identifier such as "junk-line"
+	Line     int    // 1-based input line, 0 if not tied to a line
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return parser.Diagnostic{Severity: parser.Severity(d.Severity), Code: d.Code, Line: d.Line, Message: d.Message}.String()
+}
+
+// Tree is a parsed spec. Root is the directory the spec describes (Path
+// "."); its children are the top-level entries.
+type Tree struct {
+	Root        *Node
+	Format      string // how the input was read, e.g. "text" or "tree-json"
+	Diagnostics []Diagnostic
+}
+
+// Options controls Parse.
+type Options struct {
+	// Format forces an input format (see InputFormats). Empty detects it.
+	Format string
+
+	// Block picks the 1-based fenced code block when the input is Markdown,
+	// such as a chat answer. 0 picks the most tree-like block.
+	Block int
+}
+
+// InputFormats lists the formats Options.Format accepts.
+func InputFormats() []string {
+	return parser.Formats()
+}
+
+// Formats lists the formats Render writes.
+func Formats() []string {
+	return append([]string(nil), printer.Formats...)
+}
+
+// New returns an empty tree.
+func New() *Tree {
+	return &Tree{Root: &Node{Name: ".", Path: ".", Kind: Dir}}
+}
+
+// Parse reads a spec. Problems that could be worked around are reported in
+// Tree.Diagnostics; an error means the input could not be read as asked
+// (a forced format that does not decode, or a Block that does not exist).
+func Parse(r io.Reader, opts Options) (*Tree, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	res, err := parser.ParseWithOptions(string(data), parser.Options{Block: opts.Block, Format: opts.Format})
+	if err != nil {
+		return nil, err
+	}
+
+	t := New()
+	t.Format = res.Format
+	for _, d := range res.Diagnostics {
+		t.Diagnostics = append(t.Diagnostics, Diagnostic{Severity: string(d.Severity), Code: d.Code, Line: d.Line, Message: d.Message})
+	}
+	for _, pn := range res.Nodes {
+		n := t.Add(pn.Path, Kind(pn.Kind))
+		n.Content, n.Mode, n.Template, n.Size, n.Line = pn.Content, pn.Mode, pn.Template, pn.Size, pn.Line
+		n.Description, n.Target = pn.Description, pn.Target
+	}
+	return t, nil
+}
+
+// Add returns the node at the slash path p, creating it and any missing
+// parent directories. An existing node keeps its kind.
+func (t *Tree) Add(p string, kind Kind) *Node {
+	p = strings.Trim(path.Clean(p), "/")
+	if p == "." || p == "" {
+		return t.Root
+	}
+	parent := t.Add(path.Dir(p), Dir)
+	if n := child(parent, path.Base(p)); n != nil {
+		return n
+	}
+	n := &Node{Name: path.Base(p), Path: p, Kind: kind}
+	parent.Children = append(parent.Children, n)
+	return n
+}
+
+// Find returns the node at the slash path p, or nil.
+func (t *Tree) Find(p string) *Node {
+	p = strings.Trim(path.Clean(p), "/")
+	if p == "." || p == "" {
+		return t.Root
+	}
+	parent := t.Find(path.Dir(p))
+	if parent == nil {
+		return nil
+	}
+	return child(parent, path.Base(p))
+}
+
+func child(dir *Node, name string) *Node {
+	for _, c := range dir.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Walk calls fn for every node below Root, parents before their children,
+// with depth 0 for the top-level entries. An error from fn stops the walk.
+func (t *Tree) Walk(fn func(n *Node, depth int) error) error {
+	var walk func(list []*Node, depth int) error
+	walk = func(list []*Node, depth int) error {
+		for _, n := range list {
+			if err := fn(n, depth); err != nil {
+				return err
+			}
+			if err := walk(n.Children, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(t.Root.Children, 0)
+}
+
+// Nodes returns the flat list of nodes in Walk order.
+func (t *Tree) Nodes() []*Node {
+	var nodes []*Node
+	t.Walk(func(n *Node, _ int) error {
+		nodes = append(nodes, n)
+		return nil
+	})
+	return nodes
+}
+
+// flat converts the tree into parser nodes, taking paths from the names so
+// that edits made in code are picked up.
+func (t *Tree) flat() []parser.Node {
+	var nodes []parser.Node
+	var walk func(dir string, list []*Node)
+	walk = func(dir string, list []*Node) {
+		for _, n := range list {
+			p := path.Join(dir, n.Name)
+			nodes = append(nodes, parser.Node{
+				Path: p, Kind: parser.NodeKind(n.Kind), Line: n.Line,
+				Content: n.Content, Mode: n.Mode, Template: n.Template, Size: n.Size,
+				Description: n.Description, Target: n.Target,
+			})
+			walk(p, n.Children)
+		}
+	}
+	walk("", t.Root.Children)
+	return nodes
+}
+
+// Render writes the tree in one of Formats: "tree", "markdown", "json",
+// "yaml", "paths", "html", or a diagram ("mermaid", "mindmap", "dot", "svg").
+func (t *Tree) Render(w io.Writer, format string) error {
+	if err := printer.Render(w, t.flat(), format, printer.Options{Style: "unicode"}); err != nil {
+		return fmt.Errorf("tree: %w", err)
+	}
+	return nil
+}
+
+// String renders the tree in the default format.
+func (t *Tree) String() string {
+	var b strings.Builder
+	t.Render(&b, "tree")
+	return b.String()
+}
@@ -0,0 +1,157 @@
+package tree_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cytificlabs/tr2rl/tree"
+)
+
+const spec = `app/
+├── cmd/
+│   └── main.go
+└── README.md
+`
+
+func TestParse(t *testing.T) {
+	tr, err := tree.Parse(strings.NewReader(spec), tree.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Format != "text" {
+		t.Errorf("Format = %q", tr.Format)
+	}
+	app := tr.Find("app")
+	if app == nil || app.Kind != tree.Dir || len(app.Children) != 2 {
+		t.Fatalf("app = %+v", app)
+	}
+	main := tr.Find("app/cmd/main.go")
+	if main == nil || main.Kind != tree.File || main.Name != "main.go" || main.Line != 3 {
+		t.Errorf("main.go = %+v", main)
+	}
+
+	var paths []string
+	for _, n := range tr.Nodes() {
+		paths = append(paths, n.Path)
+	}
+	if got := strings.Join(paths, " "); got != "app app/cmd app/cmd/main.go app/README.md" {
+		t.Errorf("Nodes() = %s", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := tree.Parse(strings.NewReader("{oops"), tree.Options{Format: "json"}); err == nil {
+		t.Error("expected an error for a forced format that does not decode")
+	}
+	if _, err := tree.Parse(strings.NewReader("a.txt"), tree.Options{Block: 2}); err == nil {
+		t.Error("expected an error for a missing block")
+	}
+}
+
+func TestRender(t *testing.T) {
+	tr := tree.New()
+	tr.Add("src/main.go", tree.File).Content = "package main\n"
+	tr.Add("docs", tree.Dir)
+
+	var b strings.Builder
+	if err := tr.Render(&b, "paths"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "src/\nsrc/main.go\ndocs/\n" {
+		t.Errorf("paths:\n%s", b.String())
+	}
+	if err := tr.Render(&b, "nope"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestApply(t *testing.T) {
+	tr, _ := tree.Parse(strings.NewReader(spec), tree.Options{})
+	tr.Find("app/README.md").Content = "# app\n"
+	tr.Add("app/main.go", tree.Symlink).Target = "cmd/main.go"
+
+	mem := tree.NewMemFS()
+	if err := tr.Apply(context.Background(), mem, tree.ApplyOptions{Populate: true}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := mem.ReadFile("app/README.md"); string(data) != "# app\n" {
+		t.Errorf("README.md = %q", data)
+	}
+	if data, _ := mem.ReadFile("app/cmd/main.go"); !strings.HasPrefix(string(data), "package main") {
+		t.Errorf("main.go was not populated: %q", data)
+	}
+	if target, _ := mem.Readlink("app/main.go"); target != "cmd/main.go" {
+		t.Errorf("app/main.go -> %q", target)
+	}
+
+	dir := t.TempDir()
+	var log strings.Builder
+	if err := tr.Apply(context.Background(), tree.DirFS(dir), tree.ApplyOptions{Log: &log}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app", "cmd", "main.go")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Readlink(filepath.Join(dir, "app", "main.go")); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(log.String(), "[OK] Created app/cmd/main.go") {
+		t.Errorf("log:\n%s", log.String())
+	}
+}
+
+func TestApplyMissingRoot(t *testing.T) {
+	tr, err := tree.Parse(strings.NewReader("README.md\napp/\n  main.go\n"), tree.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(t.TempDir(), "out", "nested")
+	if err := tr.Apply(context.Background(), tree.DirFS(root), tree.ApplyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"README.md", "app/main.go"} {
+		if _, err := os.Stat(filepath.Join(root, p)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestApplyCancelled(t *testing.T) {
+	tr, _ := tree.Parse(strings.NewReader(spec), tree.Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mem := tree.NewMemFS()
+	if err := tr.Apply(ctx, mem, tree.ApplyOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if len(mem.Changes()) != 0 {
+		t.Errorf("wrote %v after cancel", mem.Changes())
+	}
+}
+
+func Example() {
+	tr, err := tree.Parse(strings.NewReader("svc/\n  main.go\n  Dockerfile\n"), tree.Options{})
+	if err != nil {
+		panic(err)
+	}
+	mem := tree.NewMemFS()
+	if err := tr.Apply(context.Background(), mem, tree.ApplyOptions{}); err != nil {
+		panic(err)
+	}
+	for _, c := range mem.Changes() {
+		fmt.Println(c.Path)
+	}
+	tr.Render(os.Stdout, "tree")
+	// Output:
+	// svc
+	// svc/main.go
+	// svc/Dockerfile
+	// └── svc/
+	//     ├── Dockerfile
+	//     └── main.go
+}